@@ -24,6 +24,8 @@ func main() {
 			cmd.CodeCommand(),
 			cmd.ReviewCommand(),
 			cmd.RollbackCommand(),
+			cmd.ReplayStepCommand(),
+			cmd.CommitCommand(),
 			cmd.EndSessionCommand(),
 		},
 	}
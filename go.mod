@@ -6,6 +6,9 @@ toolchain go1.23.2
 
 require (
 	github.com/google/uuid v1.6.0
+	github.com/pkoukk/tiktoken-go v0.1.7
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/teilomillet/gollm v0.1.4
 	github.com/urfave/cli/v2 v2.27.5
 	gotest.tools/v3 v3.5.2
 )
@@ -25,16 +28,12 @@ require (
 	github.com/invopop/jsonschema v0.12.0 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
-	github.com/pkoukk/tiktoken-go v0.1.7 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
-	github.com/teilomillet/gollm v0.1.4 // indirect
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
 	golang.org/x/crypto v0.32.0 // indirect
-	golang.org/x/exp v0.0.0-20250207012021-f9890c6ad9f3 // indirect
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
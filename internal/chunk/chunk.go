@@ -0,0 +1,245 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+// Package chunk splits files that are too large for one llm call into sections
+// that can be edited separately and joined back together.
+package chunk
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/tokens"
+)
+
+// Chunk is a contiguous section of a file. The chunks of a file cover all of it, in order.
+type Chunk struct {
+	Name      string // What the section holds, e.g. "func Load" or "lines 1-80"
+	StartLine int
+	EndLine   int
+	Content   string
+}
+
+// Split divides content into chunks of at most maxTokens tokens where possible. Go files
+// are split between top-level declarations, other files and oversized declarations by lines.
+func Split(path, content string, maxTokens int, count tokens.Counter) []Chunk {
+	if filepath.Ext(path) == ".go" {
+		if chunks := Declarations(path, content); chunks != nil {
+			return group(chunks, maxTokens, count)
+		}
+	}
+
+	return splitLines(Chunk{StartLine: 1, Content: content}, maxTokens, count)
+}
+
+// Join puts the chunks of a file back together.
+func Join(chunks []Chunk) string {
+	var b strings.Builder
+
+	for _, chunk := range chunks {
+		b.WriteString(chunk.Content)
+	}
+
+	return b.String()
+}
+
+// Declarations splits a Go file into one chunk for the package clause and imports and one
+// for each following top-level declaration, including its doc comment. Returns nil when
+// the file doesn't parse.
+func Declarations(path, content string) []Chunk {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	tokenFile := fset.File(file.Pos())
+	starts := []int{0}
+	names := []string{"package and imports"}
+
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+
+		pos := decl.Pos()
+		if doc := declDoc(decl); doc != nil {
+			pos = doc.Pos()
+		}
+
+		// Sections start at the beginning of a line so indentation stays with them
+		line := tokenFile.Line(pos)
+		start := tokenFile.Offset(tokenFile.LineStart(line))
+
+		if start <= starts[len(starts)-1] {
+			continue
+		}
+
+		starts = append(starts, start)
+		names = append(names, declName(decl))
+	}
+
+	chunks := make([]Chunk, 0, len(starts))
+
+	for i, start := range starts {
+		end := len(content)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+
+		chunks = append(chunks, newChunk(names[i], content[start:end], strings.Count(content[:start], "\n")+1))
+	}
+
+	return chunks
+}
+
+// declDoc returns the doc comment of a declaration.
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Doc
+	case *ast.GenDecl:
+		return decl.Doc
+	}
+
+	return nil
+}
+
+// declName describes a declaration, e.g. "func (s *Server) Start" or "type Config".
+func declName(decl ast.Decl) string {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv != nil && len(decl.Recv.List) > 0 {
+			return fmt.Sprintf("func (%s) %s", exprString(decl.Recv.List[0].Type), decl.Name.Name)
+		}
+
+		return "func " + decl.Name.Name
+	case *ast.GenDecl:
+		names := []string{}
+
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+
+		return decl.Tok.String() + " " + strings.Join(names, ", ")
+	}
+
+	return "declaration"
+}
+
+// exprString renders a receiver type such as *Server.
+func exprString(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + exprString(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	case *ast.IndexExpr:
+		return exprString(expr.X)
+	case *ast.IndexListExpr:
+		return exprString(expr.X)
+	}
+
+	return "?"
+}
+
+// group merges neighboring chunks while they fit in maxTokens and splits chunks that
+// are too large on their own into line windows. Token counts of the parts are added
+// up rather than recounted, which is close enough for sizing.
+func group(chunks []Chunk, maxTokens int, count tokens.Counter) []Chunk {
+	grouped := []Chunk{}
+	size := 0
+
+	// Names of the chunks merged into the last group, nil when it can't take more
+	var names []string
+
+	for _, chunk := range chunks {
+		chunkSize := count(chunk.Content)
+
+		if chunkSize > maxTokens {
+			grouped = append(grouped, splitLines(chunk, maxTokens, count)...)
+			names = nil
+
+			continue
+		}
+
+		if last := len(grouped) - 1; names != nil && size+chunkSize <= maxTokens {
+			names = append(names, chunk.Name)
+			grouped[last].Name = strings.Join(names, "; ")
+			grouped[last].Content += chunk.Content
+			grouped[last].EndLine = chunk.EndLine
+			size += chunkSize
+
+			continue
+		}
+
+		grouped = append(grouped, chunk)
+		names = []string{chunk.Name}
+		size = chunkSize
+	}
+
+	return grouped
+}
+
+// splitLines divides a chunk into windows of whole lines of at most maxTokens tokens.
+// A single line longer than that becomes a window of its own.
+func splitLines(chunk Chunk, maxTokens int, count tokens.Counter) []Chunk {
+	windows := []Chunk{}
+	startLine, size := chunk.StartLine, 0
+
+	var b strings.Builder
+
+	for i, line := range strings.SplitAfter(chunk.Content, "\n") {
+		lineSize := count(line)
+
+		if b.Len() > 0 && size+lineSize > maxTokens {
+			windows = append(windows, newChunk("", b.String(), startLine))
+			b.Reset()
+
+			startLine, size = chunk.StartLine+i, 0
+		}
+
+		b.WriteString(line)
+		size += lineSize
+	}
+
+	if b.Len() > 0 || len(windows) == 0 {
+		windows = append(windows, newChunk("", b.String(), startLine))
+	}
+
+	// Windows of a declaration keep its name so the outline stays readable
+	if chunk.Name != "" {
+		for i := range windows {
+			windows[i].Name = chunk.Name
+			if len(windows) > 1 {
+				windows[i].Name = fmt.Sprintf("%s (part %d of %d)", chunk.Name, i+1, len(windows))
+			}
+		}
+	}
+
+	return windows
+}
+
+// newChunk creates a chunk starting at startLine, named after its lines when name is empty.
+func newChunk(name, content string, startLine int) Chunk {
+	endLine := startLine + strings.Count(strings.TrimSuffix(content, "\n"), "\n")
+
+	if name == "" {
+		name = fmt.Sprintf("lines %d-%d", startLine, endLine)
+	}
+
+	return Chunk{Name: name, StartLine: startLine, EndLine: endLine, Content: content}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package chunk_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/chunk"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+const goSource = `package store
+
+import "errors"
+
+var ErrMissing = errors.New("missing")
+
+// Store keeps values.
+type Store struct {
+	values map[string]string
+}
+
+// Get returns a value.
+func (s *Store) Get(key string) (string, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return "", ErrMissing
+	}
+
+	return value, nil
+}
+`
+
+// countLines counts one token per line so sizes are easy to reason about.
+func countLines(text string) int {
+	return strings.Count(text, "\n")
+}
+
+// names returns the names of the chunks.
+func names(chunks []chunk.Chunk) []string {
+	result := []string{}
+	for _, c := range chunks {
+		result = append(result, c.Name)
+	}
+
+	return result
+}
+
+// TestSplit_GoDeclarations verifies Go files split between declarations, keeping doc comments.
+func TestSplit_GoDeclarations(t *testing.T) {
+	chunks := chunk.Split("store.go", goSource, 6, countLines)
+
+	assert.DeepEqual(t, names(chunks), []string{
+		"package and imports; var ErrMissing",
+		"type Store",
+		"func (*Store) Get (part 1 of 2)",
+		"func (*Store) Get (part 2 of 2)",
+	})
+	assert.Assert(t, strings.HasPrefix(chunks[1].Content, "// Store keeps values.\n"))
+	assert.Equal(t, chunks[1].StartLine, 7)
+	assert.Equal(t, chunks[1].EndLine, 11)
+	assert.Equal(t, chunk.Join(chunks), goSource)
+}
+
+// TestSplit_Groups verifies small declarations share a chunk when they fit.
+func TestSplit_Groups(t *testing.T) {
+	chunks := chunk.Split("store.go", goSource, 100, countLines)
+
+	assert.Assert(t, cmp.Len(chunks, 1))
+	assert.Equal(t, chunks[0].Content, goSource)
+}
+
+// TestSplit_Lines verifies other files and unparsable Go split into line windows.
+func TestSplit_Lines(t *testing.T) {
+	content := strings.Repeat("line\n", 10)
+
+	for _, path := range []string{"notes.txt", "broken.go"} {
+		chunks := chunk.Split(path, content, 4, countLines)
+
+		assert.DeepEqual(t, names(chunks), []string{"lines 1-4", "lines 5-8", "lines 9-10"})
+		assert.Equal(t, chunk.Join(chunks), content)
+	}
+}
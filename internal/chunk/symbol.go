@@ -0,0 +1,133 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package chunk
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// Symbol errors.
+var (
+	ErrSymbolNotFound = errors.New("symbol not found")
+	ErrParseFailed    = errors.New("failed to parse go file")
+)
+
+// Isolate splits a Go file into the declaration of symbol, including its doc comment,
+// and the chunks before and after it, so the declaration can be replaced while the
+// rest of the file is kept byte for byte. The symbol is a function or type name,
+// Type.Method, pkg.Name or pkg.Type.Method. Returns the chunks and the index of
+// the declaration chunk.
+func Isolate(path, content, symbol string) ([]Chunk, int, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrParseFailed, err)
+	}
+
+	receiver, name, ok := splitSymbol(file.Name.Name, symbol)
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s in %s", ErrSymbolNotFound, symbol, path)
+	}
+
+	node, doc := findDecl(file, receiver, name)
+	if node == nil {
+		return nil, 0, fmt.Errorf("%w: %s in %s", ErrSymbolNotFound, symbol, path)
+	}
+
+	tokenFile := fset.File(file.Pos())
+
+	pos := node.Pos()
+	if doc != nil {
+		pos = doc.Pos()
+	}
+
+	// Whole lines are taken so indentation and the trailing newline stay with the declaration
+	start := tokenFile.Offset(tokenFile.LineStart(tokenFile.Line(pos)))
+	end := tokenFile.Offset(node.End())
+
+	if newline := strings.IndexByte(content[end:], '\n'); newline >= 0 {
+		end += newline + 1
+	} else {
+		end = len(content)
+	}
+
+	startLine := tokenFile.Line(pos)
+	chunks := []Chunk{
+		newChunk("before "+symbol, content[:start], 1),
+		newChunk(symbol, content[start:end], startLine),
+		newChunk("after "+symbol, content[end:], tokenFile.Line(node.End())+1),
+	}
+
+	return chunks, 1, nil
+}
+
+// splitSymbol separates an optional package qualifier and receiver type from the name.
+// Reports false when a package qualifier doesn't match the file's package.
+func splitSymbol(pkg, symbol string) (receiver, name string, ok bool) {
+	parts := strings.Split(symbol, ".")
+
+	switch len(parts) {
+	case 1:
+		return "", parts[0], true
+	case 2:
+		// pkg.Name when the qualifier is the package, otherwise Type.Method
+		if parts[0] == pkg {
+			return "", parts[1], true
+		}
+
+		return parts[0], parts[1], true
+	case 3:
+		return parts[1], parts[2], parts[0] == pkg
+	}
+
+	return "", "", false
+}
+
+// findDecl returns the function, method or type declaration and its doc comment.
+// Types declared in a group are returned as their spec.
+func findDecl(file *ast.File, receiver, name string) (ast.Node, *ast.CommentGroup) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Name.Name != name {
+				continue
+			}
+
+			declReceiver := ""
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				declReceiver = strings.TrimPrefix(exprString(decl.Recv.List[0].Type), "*")
+			}
+
+			if declReceiver == receiver {
+				return decl, decl.Doc
+			}
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE || receiver != "" {
+				continue
+			}
+
+			for _, spec := range decl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok || typeSpec.Name.Name != name {
+					continue
+				}
+
+				if len(decl.Specs) == 1 && !decl.Lparen.IsValid() {
+					return decl, decl.Doc
+				}
+
+				return typeSpec, typeSpec.Doc
+			}
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package chunk_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/chunk"
+	"gotest.tools/v3/assert"
+)
+
+// TestIsolate verifies declarations are found by every supported symbol form.
+func TestIsolate(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{"Store", "// Store keeps values.\ntype Store struct {\n\tvalues map[string]string\n}\n"},
+		{"store.Store", "// Store keeps values.\ntype Store struct {\n\tvalues map[string]string\n}\n"},
+		{"Store.Get", "// Get returns a value.\nfunc (s *Store) Get("},
+		{"store.Store.Get", "// Get returns a value.\nfunc (s *Store) Get("},
+	}
+
+	for _, tt := range tests {
+		chunks, index, err := chunk.Isolate("store.go", goSource, tt.symbol)
+		assert.NilError(t, err, tt.symbol)
+		assert.Assert(t, strings.HasPrefix(chunks[index].Content, tt.want), tt.symbol)
+		assert.Equal(t, chunk.Join(chunks), goSource)
+	}
+}
+
+// TestIsolate_Splice verifies replacing the declaration keeps the rest of the file byte for byte.
+func TestIsolate_Splice(t *testing.T) {
+	chunks, index, err := chunk.Isolate("store.go", goSource, "Store")
+	assert.NilError(t, err)
+	assert.Equal(t, chunks[index].StartLine, 7)
+
+	chunks[index].Content = "type Store struct{}\n"
+	want := strings.Replace(goSource,
+		"// Store keeps values.\ntype Store struct {\n\tvalues map[string]string\n}\n", "type Store struct{}\n", 1)
+	assert.Equal(t, chunk.Join(chunks), want)
+}
+
+// TestIsolate_NotFound ensures missing symbols and other packages are reported.
+func TestIsolate_NotFound(t *testing.T) {
+	for _, symbol := range []string{"Missing", "Store.Put", "other.Store.Get", "Get"} {
+		_, _, err := chunk.Isolate("store.go", goSource, symbol)
+		assert.Assert(t, errors.Is(err, chunk.ErrSymbolNotFound), "%s: expected ErrSymbolNotFound, got: %v", symbol, err)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/chrisrob11/codeassistant/internal/tokens"
+)
+
+// Token budget errors.
+var (
+	ErrPromptTooLarge = errors.New("prompt does not fit in the model context")
+)
+
+// Smallest completion limit chosen when sizing it from the input.
+const minOutputTokens = 1024
+
+// Files over a quarter of the context window are edited in chunks by default.
+const chunkContextShare = 4
+
+// Share of the context window a prompt may use before a warning is shown, in percent.
+const contextWarnPercent = 80
+
+// tokenBudget holds the limits llm calls are checked against before they are sent.
+type tokenBudget struct {
+	Count           tokens.Counter
+	ContextTokens   int  // Prompt and response together
+	MaxOutputTokens int  // Completion limit sent to the llm
+	IgnoreLimit     bool // Warn instead of refusing prompts that don't fit
+}
+
+// count returns the tokens in text, estimating them when there is no budget.
+func (b *tokenBudget) count(text string) int {
+	if b == nil || b.Count == nil {
+		return tokens.Estimate(text)
+	}
+
+	return b.Count(text)
+}
+
+// check warns when a response may be truncated and refuses a prompt that leaves no
+// room for the response. Checks are skipped without a budget.
+func (b *tokenBudget) check(label, prompt string, expectedOutput int) error {
+	if b == nil || b.ContextTokens <= 0 {
+		return nil
+	}
+
+	promptTokens := b.count(prompt)
+
+	if promptTokens+b.MaxOutputTokens > b.ContextTokens {
+		err := fmt.Errorf("%w: %s needs %d prompt tokens plus %d for the response but the model allows %d "+
+			"(try --per-file or fewer files)", ErrPromptTooLarge, label, promptTokens, b.MaxOutputTokens, b.ContextTokens)
+		if !b.IgnoreLimit {
+			return err
+		}
+
+		fmt.Printf("⚠️  %v\n", err)
+	} else if promptTokens*100 > b.ContextTokens*contextWarnPercent {
+		fmt.Printf("⚠️  %s uses %d of the %d tokens the model allows\n", label, promptTokens, b.ContextTokens)
+	}
+
+	if expectedOutput > b.MaxOutputTokens {
+		fmt.Printf("⚠️  %s may need about %d tokens for the response but llm-max-tokens is %d, "+
+			"so it may be truncated\n", label, expectedOutput, b.MaxOutputTokens)
+	}
+
+	return nil
+}
+
+// expectedOutputTokens estimates the size of a response that edits input of the given size.
+func expectedOutputTokens(format string, inputTokens int) int {
+	if format == EditFormatWhole {
+		// The whole file comes back, with some room for additions
+		return inputTokens + inputTokens/4
+	}
+
+	// Search/replace blocks repeat the lines they change and the replacement
+	return inputTokens / 2
+}
+
+// newTokenBudget counts tokens for the configured model. Unless they were set, it also
+// sizes the chunking threshold from the context window and the completion limit from
+// the files in the request.
+func newTokenBudget(ctx context.Context, llmConfig *LLMConfig, req *codeRequest, ignoreLimit bool) *tokenBudget {
+	if llmConfig.ContextTokens == 0 {
+		llmConfig.ContextTokens = tokens.ContextWindow(llmConfig.Model)
+	}
+
+	count, err := tokens.ForModel(ctx, llmConfig.Model)
+	if err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	budget := &tokenBudget{
+		Count:         count,
+		ContextTokens: llmConfig.ContextTokens,
+		IgnoreLimit:   ignoreLimit,
+	}
+
+	if req.ChunkTokens == 0 {
+		req.ChunkTokens = budget.ContextTokens / chunkContextShare
+	}
+
+	if llmConfig.MaxTokens == 0 {
+		llmConfig.MaxTokens = sizeMaxTokens(req, budget)
+	}
+
+	budget.MaxOutputTokens = llmConfig.MaxTokens
+
+	return budget
+}
+
+// sizeMaxTokens picks a completion limit large enough for the largest llm call the
+// request makes, without taking more than half of the context window.
+func sizeMaxTokens(req *codeRequest, budget *tokenBudget) int {
+	if req.Summary {
+		return min(summaryOutputTokens, budget.ContextTokens/2)
+	}
+
+	largestOutput, batchInput := 0, 0
+
+	for _, file := range req.AbsFilePaths {
+		content, err := req.readFile(file)
+		if err != nil {
+			// Files that don't exist yet have no content to send
+			continue
+		}
+
+		fileTokens := budget.count(string(content))
+
+		switch {
+		case req.ChunkTokens > 0 && fileTokens > req.ChunkTokens:
+			// Large files are edited a chunk at a time with search/replace blocks
+			largestOutput = max(largestOutput, expectedOutputTokens(EditFormatSearchReplace, req.ChunkTokens))
+		case req.PerFile:
+			largestOutput = max(largestOutput, expectedOutputTokens(req.EditFormat, fileTokens))
+		default:
+			batchInput += fileTokens
+		}
+	}
+
+	largestOutput = max(largestOutput, expectedOutputTokens(req.EditFormat, batchInput))
+
+	return max(minOutputTokens, min(largestOutput, budget.ContextTokens/2))
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/chunk"
+	"github.com/teilomillet/gollm"
+)
+
+// A reply to the chunk selection prompt: section numbers separated by commas.
+var sectionListPattern = regexp.MustCompile(`^\d+(\s*,\s*\d+)*$`)
+
+// isOversized reports whether a file is too large for one llm call and is edited in chunks.
+func (req *codeRequest) isOversized(content string) bool {
+	return req.ChunkTokens > 0 && req.Budget.count(content) > req.ChunkTokens
+}
+
+// modifyChunked edits a file that is too large for one llm call. The file is split into
+// chunks, the llm picks the chunks the change touches, each of those is edited on its
+// own and the chunks are joined back together.
+func modifyChunked(
+	ctx context.Context, llm gollm.LLM, req *codeRequest, relPath, content string, refs *promptReferences,
+) (string, error) {
+	chunks := chunk.Split(relPath, content, req.ChunkTokens, req.Budget.count)
+
+	selected, err := selectChunks(ctx, llm, req, relPath, chunks)
+	if err != nil {
+		return "", err
+	}
+
+	for _, i := range selected {
+		section := chunks[i]
+		label := fmt.Sprintf("%s lines %d-%d", relPath, section.StartLine, section.EndLine)
+		fullPrompt := buildChunkEditPrompt(req.Prompt, relPath, chunks, i, refs)
+
+		expectedOutput := expectedOutputTokens(EditFormatSearchReplace, req.Budget.count(section.Content))
+		if err := req.Budget.check(label, fullPrompt, expectedOutput); err != nil {
+			return "", err
+		}
+
+		response, err := processWithLLM(ctx, llm, fullPrompt)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", label, err)
+		}
+
+		edited, err := applyResponse(EditFormatSearchReplace, relPath, section.Content, response)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %w", ErrEditNotApplied, label, err)
+		}
+
+		// The next chunk must still start on a line of its own
+		if strings.HasSuffix(section.Content, "\n") && !strings.HasSuffix(edited, "\n") {
+			edited += "\n"
+		}
+
+		chunks[i].Content = edited
+	}
+
+	return chunk.Join(chunks), nil
+}
+
+// selectChunks asks the llm which chunks the change touches and returns their indexes
+// in file order. A reply that isn't a plain list of section numbers selects every chunk.
+func selectChunks(
+	ctx context.Context, llm gollm.LLM, req *codeRequest, relPath string, chunks []chunk.Chunk,
+) ([]int, error) {
+	all := make([]int, 0, len(chunks))
+	for i := range chunks {
+		all = append(all, i)
+	}
+
+	if len(chunks) == 1 {
+		return all, nil
+	}
+
+	response, err := processWithLLM(ctx, llm, buildChunkSelectPrompt(req.Prompt, relPath, chunks))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", relPath, err)
+	}
+
+	selected, ok := parseChunkSelection(response, len(chunks))
+	if !ok {
+		return all, nil
+	}
+
+	return selected, nil
+}
+
+// parseChunkSelection reads a reply that is exactly a comma separated list of section
+// numbers, or "none", into chunk indexes in file order. Anything else, such as prose
+// that mentions line numbers or a number past the last section, isn't trusted and
+// reports false.
+func parseChunkSelection(response string, count int) ([]int, bool) {
+	reply := strings.Trim(strings.TrimSpace(response), "`.")
+
+	if strings.EqualFold(reply, "none") {
+		return []int{}, true
+	}
+
+	if !sectionListPattern.MatchString(reply) {
+		return nil, false
+	}
+
+	picked := make([]bool, count)
+
+	for _, field := range strings.Split(reply, ",") {
+		number, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || number < 1 || number > count {
+			return nil, false
+		}
+
+		picked[number-1] = true
+	}
+
+	selected := []int{}
+
+	for i, ok := range picked {
+		if ok {
+			selected = append(selected, i)
+		}
+	}
+
+	return selected, true
+}
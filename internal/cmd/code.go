@@ -8,11 +8,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/chrisrob11/codeassistant/internal/edit"
+	"github.com/chrisrob11/codeassistant/internal/fileselect"
+	"github.com/chrisrob11/codeassistant/internal/filetx"
 	"github.com/chrisrob11/codeassistant/internal/session"
 	"github.com/teilomillet/gollm"
 	cli "github.com/urfave/cli/v2"
@@ -27,9 +34,13 @@ var (
 	ErrFailedToLoadSession    = errors.New("failed to load session")
 	ErrFailedToSaveSession    = errors.New("failed to save session")
 	ErrFileOutsideCurrentDir  = errors.New("file is outside the current directory")
+	ErrDanglingSymlink        = errors.New("symlink points to a missing file")
 	ErrFailedToResolveAbsPath = errors.New("failed to resolve absolute path")
 	ErrFailedToReadFile       = errors.New("failed to read file")
 	ErrFilesMustBeSpecified   = errors.New("failed as files not specified")
+	ErrEditNotApplied         = errors.New("failed to apply AI edits")
+	ErrUnexpectedFile         = errors.New("AI response changes a file that was not requested")
+	ErrProtectedFile          = errors.New("AI response changes a file managed by git or the session")
 )
 
 // CodeCommand applies AI modifications to code.
@@ -41,12 +52,64 @@ func CodeCommand() *cli.Command {
 			&cli.StringSliceFlag{
 				Name:    "files",
 				Aliases: []string{"f"},
-				Usage:   "Specify files to modify",
+				Usage:   "Files, directories or globs such as internal/**/*.go to modify",
+			},
+			&cli.StringSliceFlag{
+				Name:    "context",
+				Aliases: []string{"c"},
+				Usage:   "Read-only files included in the prompt as reference, never modified",
+				EnvVars: []string{"CA_CONTEXT"},
+			},
+			&cli.IntFlag{
+				Name:    "repo-map-tokens",
+				Value:   defaultRepoMapTokens,
+				Usage:   "Token budget for the summary of Go symbols added to prompts, 0 disables it",
+				EnvVars: []string{"CA_REPO_MAP_TOKENS"},
+			},
+			&cli.IntFlag{
+				Name:    "chunk-tokens",
+				Usage:   "Edit files larger than this many tokens in chunks, sized from the model context when not set",
+				EnvVars: []string{"CA_CHUNK_TOKENS"},
+			},
+			&cli.BoolFlag{
+				Name:  "ignore-token-limit",
+				Usage: "Send prompts even when they may not fit in the model context",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "Files, directories or globs to leave out of --files",
+			},
+			&cli.BoolFlag{
+				Name:  "changed",
+				Usage: "Also modify the files that differ from git HEAD",
+			},
+			&cli.StringSliceFlag{
+				Name:  "symbol",
+				Usage: "Only change this Go declaration, e.g. pkg.Func or Type.Method",
 			},
 			&cli.BoolFlag{
 				Name:  "per-file",
 				Usage: "Apply the prompt to each file individually",
 			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   4,
+				Usage:   "Maximum number of files processed at once with --per-file",
+				EnvVars: []string{"CA_JOBS"},
+			},
+			&cli.BoolFlag{
+				Name:  "summary",
+				Usage: "Ask for an analysis of the files instead of modifying them",
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: "Write the --summary analysis to this file instead of printing it",
+			},
+			&cli.BoolFlag{
+				Name:  "store-session",
+				Usage: "Store the --summary analysis as a step in the session",
+			},
 			&cli.BoolFlag{
 				Name:  "dry-run",
 				Usage: "Preview AI-generated changes without modifying files",
@@ -55,11 +118,56 @@ func CodeCommand() *cli.Command {
 				Name:  "revise",
 				Usage: "Modify the last step instead of creating a new one",
 			},
+			&cli.BoolFlag{
+				Name:    "allow-dirty",
+				Usage:   "Modify files that have uncommitted changes not made by the session",
+				EnvVars: []string{"CA_ALLOW_DIRTY"},
+			},
+			&cli.BoolFlag{
+				Name:    "allow-untracked",
+				Usage:   "Modify files that are not tracked by git",
+				EnvVars: []string{"CA_ALLOW_UNTRACKED"},
+			},
+			&cli.BoolFlag{
+				Name:  "commit",
+				Usage: "Commit the files changed by the session steps with a generated message",
+			},
+			&cli.BoolFlag{
+				Name:    "verify",
+				Usage:   "Check that edited Go code parses, builds and vets",
+				EnvVars: []string{"CA_VERIFY"},
+			},
+			&cli.BoolFlag{
+				Name:  "verify-rollback",
+				Usage: "Roll the step back when verification fails",
+			},
+			&cli.BoolFlag{
+				Name:  "fix-until-green",
+				Usage: "Feed check failures back to the LLM until the check passes",
+			},
+			&cli.StringFlag{
+				Name:    "check-cmd",
+				Value:   "go test ./...",
+				Usage:   "Shell command run by --fix-until-green to check the changes",
+				EnvVars: []string{"CA_CHECK_CMD"},
+			},
+			&cli.IntFlag{
+				Name:    "max-fix-attempts",
+				Value:   3,
+				Usage:   "Maximum number of fix attempts made by --fix-until-green",
+				EnvVars: []string{"CA_MAX_FIX_ATTEMPTS"},
+			},
+			&cli.StringFlag{
+				Name:    "edit-format",
+				Value:   EditFormatSearchReplace,
+				Usage:   "How the LLM returns changes (search-replace, whole)",
+				EnvVars: []string{"CA_EDIT_FORMAT"},
+			},
 		},
 		Action: func(c *cli.Context) error {
-			currentDir, err := os.Getwd()
+			currentDir, workDir, err := findCurrentDir()
 			if err != nil {
-				return fmt.Errorf("%w", ErrFailedToGetCurrentDir)
+				return err
 			}
 
 			prompt := c.Args().First()
@@ -67,20 +175,30 @@ func CodeCommand() *cli.Command {
 				return ErrMissingPrompt
 			}
 
-			files := c.StringSlice("files")
-			if len(files) == 0 {
-				return ErrFilesMustBeSpecified
+			dryRun := c.Bool("dry-run")
+
+			if !c.Bool("summary") && (c.String("output") != "" || c.Bool("store-session")) {
+				return ErrSummaryOptionWithoutSummary
 			}
 
-			absFilePaths := []string{}
-			dryRun := c.Bool("dry-run")
+			editFormat := c.String("edit-format")
+			if err := validateEditFormat(editFormat); err != nil {
+				return err
+			}
 
-			for _, f := range files {
-				absPath, err := isValidFilePath(currentDir, f)
-				if err != nil {
-					return err
-				}
-				absFilePaths = append(absFilePaths, absPath)
+			absFilePaths, err := selectCodeFiles(c, currentDir, workDir)
+			if err != nil {
+				return err
+			}
+
+			symbols, absFilePaths, err := resolveSymbols(c.StringSlice("symbol"), absFilePaths)
+			if err != nil {
+				return err
+			}
+
+			contextFiles, err := selectContextFiles(currentDir, workDir, c.StringSlice("context"), absFilePaths)
+			if err != nil {
+				return err
 			}
 
 			llmConfig := NewLLMConfigFromContext(c)
@@ -88,117 +206,712 @@ func CodeCommand() *cli.Command {
 				return err
 			}
 
+			req := &codeRequest{
+				CurrentDir:        currentDir,
+				Prompt:            prompt,
+				AbsFilePaths:      absFilePaths,
+				ContextFiles:      contextFiles,
+				Symbols:           symbols,
+				RepoMapTokens:     c.Int("repo-map-tokens"),
+				ChunkTokens:       c.Int("chunk-tokens"),
+				Model:             llmConfig.Model,
+				EditFormat:        editFormat,
+				DryRun:            dryRun,
+				Revise:            c.Bool("revise"),
+				Commit:            c.Bool("commit"),
+				AllowDirty:        c.Bool("allow-dirty"),
+				AllowUntracked:    c.Bool("allow-untracked"),
+				PerFile:           c.Bool("per-file"),
+				Jobs:              c.Int("jobs"),
+				Verify:            c.Bool("verify") || c.Bool("verify-rollback"),
+				RollbackOnFailure: c.Bool("verify-rollback"),
+				FixUntilGreen:     c.Bool("fix-until-green"),
+				CheckCommand:      c.String("check-cmd"),
+				MaxFixAttempts:    c.Int("max-fix-attempts"),
+				Summary:           c.Bool("summary"),
+				OutputFile:        fromWorkDir(workDir, c.String("output")),
+				StoreSummary:      c.Bool("store-session") || c.Bool("store-summary"),
+			}
+
+			// Sized before building the llm so the completion limit fits the files
+			req.Budget = newTokenBudget(c.Context, llmConfig, req, c.Bool("ignore-token-limit"))
+
 			llm, err := llmConfig.BuildLLM()
 			if err != nil {
 				return err
 			}
 
-			return executeCodeCommand(llm, currentDir, prompt, absFilePaths, dryRun)
+			// Ctrl-C cancels any LLM calls still in flight
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			return executeCodeCommand(ctx, llm, req)
 		},
 	}
 }
 
-func executeCodeCommand(llm gollm.LLM, currentDir, prompt string, absFilePaths []string, dryRun bool) error {
-	currentSession, err := session.LoadCurrentSession(currentDir)
+// codeRequest holds the options for applying a prompt to files.
+type codeRequest struct {
+	CurrentDir    string
+	Prompt        string
+	AbsFilePaths  []string
+	ContextFiles  []string       // Absolute paths of read-only files sent as reference
+	Symbols       []symbolTarget // Declarations to change instead of whole files
+	RepoMapTokens int            // Token budget for the summary of Go symbols, 0 leaves it out
+	Model         string
+	EditFormat    string
+	DryRun        bool
+	Revise        bool // Replace the result of the last step instead of adding a step
+	Commit        bool // Commit the session changes with a generated message afterwards
+
+	// AllowDirty and AllowUntracked let the llm change files with uncommitted human edits
+	// or files git doesn't track
+	AllowDirty     bool
+	AllowUntracked bool
+
+	// Budget checks prompts against the model limits, nil skips the checks
+	Budget *tokenBudget
+
+	// Contents replaces the content on disk of the files it holds, keyed by absolute path,
+	// so a replay can be previewed without restoring files. A nil entry is a missing file.
+	Contents map[string][]byte
+
+	// ChunkTokens is the size above which a file is edited in chunks, 0 never chunks
+	ChunkTokens int
+
+	// PerFile processes each file with its own llm call, using up to Jobs at once
+	PerFile bool
+	Jobs    int
+
+	// Verify runs the Go checks after writing, RollbackOnFailure undoes the step when they fail
+	Verify            bool
+	RollbackOnFailure bool
+
+	// FixUntilGreen sends failures of CheckCommand back to the llm up to MaxFixAttempts times
+	FixUntilGreen  bool
+	CheckCommand   string
+	MaxFixAttempts int
+
+	// Summary asks for an analysis instead of changes, written to OutputFile when set
+	// and stored as a session step with StoreSummary
+	Summary      bool
+	OutputFile   string
+	StoreSummary bool
+}
+
+// readFile returns the content of a file to send to the llm, from Contents when it's held there.
+func (req *codeRequest) readFile(file string) ([]byte, error) {
+	if content, ok := req.Contents[file]; ok {
+		if content == nil {
+			return nil, &fs.PathError{Op: "open", Path: file, Err: fs.ErrNotExist}
+		}
+
+		return content, nil
+	}
+
+	// nolint:gosec //Why: files are validated within a specific path
+	return os.ReadFile(file)
+}
+
+func executeCodeCommand(ctx context.Context, llm gollm.LLM, req *codeRequest) error {
+	if req.Summary {
+		return executeSummary(ctx, llm, req)
+	}
+
+	currentSession, err := session.LoadCurrentSession(req.CurrentDir)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrFailedToLoadSession, err)
 	}
 
+	if req.Revise {
+		err = reviseLastStep(ctx, llm, currentSession, req)
+	} else {
+		err = runCodeStep(ctx, llm, currentSession, req)
+	}
+
+	if err != nil || !req.Commit || req.DryRun {
+		return err
+	}
+
+	return commitSteps(ctx, llm, req.CurrentDir, currentSession, "")
+}
+
+// runCodeStep applies the request and records it as a new step.
+func runCodeStep(ctx context.Context, llm gollm.LLM, currentSession *session.Session, req *codeRequest) error {
+	currentDir := req.CurrentDir
+
+	if !req.DryRun {
+		if err := checkFilesClean(currentSession, req); err != nil {
+			return err
+		}
+	}
+
 	// Modify code
-	modifications, err := modifyCode(llm, prompt, absFilePaths)
+	modifications, err := modifyCode(ctx, llm, req)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrAIProcessingFailed, err)
 	}
 
 	// Handle dry-run
-	if dryRun {
-		for file, mod := range modifications {
-			fmt.Printf("Changes for %s:\n%s\n", file, mod)
+	if req.DryRun {
+		printModifications(modifications)
+		return nil
+	}
+
+	snapshots, gitStates, err := writeStepChanges(currentDir, modifications)
+	if err != nil {
+		return err
+	}
+
+	// Track changes in session
+	step := &session.Step{
+		ID:        currentSession.NextStepID(),
+		Type:      session.StepTypeCode,
+		Command:   newCommand(req),
+		Timestamp: time.Now(),
+		FilesDiff: session.NewFilesDiff(snapshots),
+		Git:       gitStates,
+	}
+	currentSession.Steps = append(currentSession.Steps, step)
+
+	err = session.SaveCurrentSession(currentDir, currentSession)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToSaveSession, err)
+	}
+
+	if req.Verify || req.FixUntilGreen {
+		return verifyStep(ctx, llm, currentSession, step, req)
+	}
+
+	return nil
+}
+
+// printModifications shows the changes a dry run would have written.
+func printModifications(modifications map[string]fileChange) {
+	for _, file := range sortedFiles(modifications) {
+		if modifications[file].Delete {
+			fmt.Printf("Delete %s\n", file)
+			continue
 		}
 
-		return nil
+		fmt.Printf("Changes for %s:\n%s\n", file, modifications[file].Content)
 	}
+}
 
-	// Write modifications
-	for file, mod := range modifications {
-		err := os.WriteFile(file, []byte(mod), 0600)
+// newCommand records the options of the request in the session.
+func newCommand(req *codeRequest) session.Command {
+	command := session.Command{
+		Prompt:       req.Prompt,
+		Files:        req.AbsFilePaths,
+		ContextFiles: req.ContextFiles,
+		Model:        req.Model,
+	}
+
+	for _, target := range req.Symbols {
+		command.Symbols = append(command.Symbols, target.Symbol)
+	}
+
+	return command
+}
+
+// writeStepChanges writes the modifications of a step and captures the git state around them.
+func writeStepChanges(
+	currentDir string, modifications map[string]fileChange,
+) ([]session.FileSnapshot, session.Git, error) {
+	gitPre, err := captureGitState(currentDir)
+	if err != nil {
+		return nil, session.Git{}, err
+	}
+
+	snapshots, err := writeModifications(currentDir, modifications)
+	if err != nil {
+		return nil, session.Git{}, err
+	}
+
+	gitPost, err := captureGitState(currentDir)
+	if err != nil {
+		return nil, session.Git{}, err
+	}
+
+	return snapshots, session.Git{Pre: gitPre, Post: gitPost}, nil
+}
+
+// writeModifications snapshots the content before and after each modification so the
+// change can be reviewed or undone, then writes the changed files as a group so a
+// failure leaves every file untouched.
+func writeModifications(currentDir string, modifications map[string]fileChange) ([]session.FileSnapshot, error) {
+	blobStore := session.NewBlobStore(currentDir)
+	snapshots := make([]session.FileSnapshot, 0, len(modifications))
+	tx := filetx.New()
+
+	for _, file := range sortedFiles(modifications) {
+		change := modifications[file]
+
+		snapshot, err := snapshotModification(blobStore, currentDir, file, change)
 		if err != nil {
-			return fmt.Errorf("%w: %v", ErrFailedToWriteChanges, err)
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+
+		switch {
+		case snapshot.PreHash == snapshot.PostHash:
+		case change.Delete:
+			tx.Delete(file)
+		default:
+			tx.Write(file, []byte(change.Content))
 		}
 	}
 
-	var lastStep *session.Step
-	for _, step := range currentSession.Steps {
-		lastStep = step
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToWriteChanges, err)
 	}
 
-	stepID := 1
-	if lastStep != nil {
-		stepID = lastStep.ID + 1
+	return snapshots, nil
+}
+
+// snapshotModification stores the current and modified content of a file in the blob store.
+func snapshotModification(
+	blobStore *session.BlobStore, currentDir, file string, change fileChange,
+) (session.FileSnapshot, error) {
+	relPath, err := filepath.Rel(currentDir, file)
+	if err != nil {
+		return session.FileSnapshot{}, fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
 	}
 
-	// Track changes in session
-	currentSession.Steps = append(currentSession.Steps, &session.Step{
-		ID:        stepID,
-		Command:   session.Command{Prompt: prompt, Files: absFilePaths},
-		Timestamp: time.Now(),
-	})
+	preHash, err := blobStore.SnapshotFile(file)
+	if err != nil {
+		return session.FileSnapshot{}, err
+	}
 
-	err = session.SaveCurrentSession(currentDir, currentSession)
+	// A deleted file has no content afterwards
+	if change.Delete {
+		return session.FileSnapshot{Path: relPath, PreHash: preHash}, nil
+	}
+
+	postHash, err := blobStore.Put([]byte(change.Content))
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrFailedToSaveSession, err)
+		return session.FileSnapshot{}, err
 	}
 
-	return nil
+	return session.FileSnapshot{Path: relPath, PreHash: preHash, PostHash: postHash}, nil
 }
 
-// Function to modify code using AI.
-func modifyCode(llm gollm.LLM, prompt string, files []string) (map[string]string, error) {
-	modifications := make(map[string]string)
+// sortedFiles returns the paths of the changes in a stable order.
+func sortedFiles(modifications map[string]fileChange) []string {
+	files := make([]string, 0, len(modifications))
+	for file := range modifications {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
 
-	for _, file := range files {
-		// nolint:gosec //Why: files are validated within a specific path
-		content, err := os.ReadFile(file)
+	return files
+}
+
+// fileChange is the new state of a file after the llm has edited it.
+type fileChange struct {
+	Content string
+	Delete  bool
+}
+
+// Function to modify code using AI. By default all files go to the llm in one batch,
+// which may also create and delete files; with PerFile they are processed by up to
+// req.Jobs workers, and the first failure or an interrupt cancels the rest.
+func modifyCode(ctx context.Context, llm gollm.LLM, req *codeRequest) (map[string]fileChange, error) {
+	refs, err := loadReferences(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Symbols) > 0 {
+		return modifySymbols(ctx, llm, req, refs)
+	}
+
+	if !req.PerFile {
+		return modifyBatch(ctx, llm, req, refs)
+	}
+
+	jobs := 1
+	if req.PerFile && req.Jobs > 1 {
+		jobs = min(req.Jobs, len(req.AbsFilePaths))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Results are kept by file index so the outcome doesn't depend on scheduling
+	results := make([]string, len(req.AbsFilePaths))
+	errs := make([]error, len(req.AbsFilePaths))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range jobs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				results[i], errs[i] = modifyFile(ctx, llm, req, req.AbsFilePaths[i], refs)
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for i := range req.AbsFilePaths {
+		if ctx.Err() != nil {
+			break
+		}
+
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	if err := joinFileErrors(ctx, errs); err != nil {
+		return nil, err
+	}
+
+	modifications := make(map[string]fileChange, len(req.AbsFilePaths))
+	for i, file := range req.AbsFilePaths {
+		modifications[file] = fileChange{Content: results[i]}
+	}
+
+	return modifications, nil
+}
+
+// joinFileErrors combines the per-file errors, leaving out files that were only
+// cancelled because another file failed.
+func joinFileErrors(ctx context.Context, errs []error) error {
+	failures := []error{}
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			failures = append(failures, err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.Join(failures...)
+	}
+
+	// Nothing failed on its own, so the work was interrupted
+	return ctx.Err()
+}
+
+// modifyFile asks the llm to apply the prompt to a single file and returns the new content.
+func modifyFile(
+	ctx context.Context, llm gollm.LLM, req *codeRequest, file string, refs *promptReferences,
+) (string, error) {
+	relPath, err := filepath.Rel(req.CurrentDir, file)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+	}
+
+	content, err := req.readFile(file)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrFailedToReadFile, relPath, err)
+	}
+
+	if req.isOversized(string(content)) {
+		return modifyChunked(ctx, llm, req, filepath.ToSlash(relPath), string(content), refs)
+	}
+
+	fullPrompt := buildEditPrompt(req.Prompt, relPath, string(content), req.EditFormat, refs)
+
+	expectedOutput := expectedOutputTokens(req.EditFormat, req.Budget.count(string(content)))
+	if err := req.Budget.check(relPath, fullPrompt, expectedOutput); err != nil {
+		return "", err
+	}
+
+	response, err := processWithLLM(ctx, llm, fullPrompt)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", relPath, err)
+	}
+
+	modifiedContent, err := applyResponse(req.EditFormat, relPath, string(content), response)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrEditNotApplied, relPath, err)
+	}
+
+	return modifiedContent, nil
+}
+
+// modifyBatch sends every file in a single prompt so related changes stay consistent,
+// then splits the response back into the new content of each file.
+func modifyBatch(
+	ctx context.Context, llm gollm.LLM, req *codeRequest, refs *promptReferences,
+) (map[string]fileChange, error) {
+	files := make([]promptFile, 0, len(req.AbsFilePaths))
+	originals := make(map[string]string, len(req.AbsFilePaths))
+	oversized := []string{}
+
+	for _, file := range req.AbsFilePaths {
+		relPath, err := filepath.Rel(req.CurrentDir, file)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrFailedToReadFile, err)
+			return nil, fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+		}
+
+		content, err := req.readFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrFailedToReadFile, relPath, err)
+		}
+
+		if req.isOversized(string(content)) {
+			oversized = append(oversized, file)
+			continue
 		}
 
-		// Call AI processing (mocking the LLM call)
-		modifiedContent, err := processWithLLM(llm, prompt, string(content))
+		files = append(files, promptFile{Path: filepath.ToSlash(relPath), Content: string(content)})
+		originals[filepath.ToSlash(relPath)] = string(content)
+	}
+
+	modifications := make(map[string]fileChange, len(req.AbsFilePaths))
+
+	// Files too large to share a prompt are edited chunk by chunk on their own
+	for _, file := range oversized {
+		content, err := modifyFile(ctx, llm, req, file, refs)
 		if err != nil {
 			return nil, err
 		}
 
-		modifications[file] = modifiedContent
+		modifications[file] = fileChange{Content: content}
+	}
+
+	if len(files) == 0 {
+		return modifications, nil
+	}
+
+	fullPrompt := buildBatchPrompt(req.Prompt, files, refs, req.EditFormat)
+
+	inputTokens := 0
+
+	for _, file := range files {
+		inputTokens += req.Budget.count(file.Content)
+	}
+
+	if err := req.Budget.check("batch prompt", fullPrompt, expectedOutputTokens(req.EditFormat, inputTokens)); err != nil {
+		return nil, err
+	}
+
+	response, err := processWithLLM(ctx, llm, fullPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := applyBatchResponse(req.EditFormat, originals, refs.ContextFiles, response)
+	if err != nil {
+		return nil, err
+	}
+
+	for relPath, change := range updates {
+		file, err := resolveResponsePath(req, relPath, originals, change)
+		if err != nil {
+			return nil, err
+		}
+
+		modifications[file] = change
+	}
+
+	// Files the llm left alone are still part of the step
+	for _, file := range req.AbsFilePaths {
+		if _, ok := modifications[file]; !ok {
+			relPath, _ := filepath.Rel(req.CurrentDir, file)
+			modifications[file] = fileChange{Content: originals[filepath.ToSlash(relPath)]}
+		}
 	}
 
 	return modifications, nil
 }
 
-// Ensure that the file is inside the current working directory.
-func isValidFilePath(currentDir, filePath string) (string, error) {
-	// Get absolute path of the file
-	absFilePath, err := filepath.Abs(filePath)
+// selectCodeFiles expands the --files, --exclude and --changed arguments into validated absolute
+// paths. Relative arguments start from the working directory but must stay in the project.
+func selectCodeFiles(c *cli.Context, currentDir, workDir string) ([]string, error) {
+	patterns := c.StringSlice("files")
+
+	if len(patterns) == 0 && !c.Bool("changed") {
+		if len(c.StringSlice("symbol")) == 0 {
+			return nil, ErrFilesMustBeSpecified
+		}
+
+		// Symbols are looked up across the project
+		patterns = []string{filepath.Join(currentDir, "**", "*.go")}
+	}
+
+	files, err := fileselect.Select(&fileselect.Options{
+		Root:     currentDir,
+		BaseDir:  workDir,
+		Patterns: patterns,
+		Exclude:  c.StringSlice("exclude"),
+		Changed:  c.Bool("changed"),
+	})
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, ErrFilesMustBeSpecified
 	}
 
-	// Ensure the file is within the current directory
-	if !strings.HasPrefix(absFilePath, currentDir) {
-		return "", fmt.Errorf("%w: %s", ErrFileOutsideCurrentDir, filePath)
+	absFilePaths := make([]string, 0, len(files))
+
+	for _, f := range files {
+		absPath, err := isValidFilePath(currentDir, f)
+		if err != nil {
+			return nil, err
+		}
+
+		absFilePaths = append(absFilePaths, absPath)
 	}
 
-	return absFilePath, nil
+	return absFilePaths, nil
 }
 
-// Use gollm to process the AI request.
-func processWithLLM(llm gollm.LLM, prompt, fileContent string) (string, error) {
-	ctx := context.Background()
+// resolveResponsePath validates a path named in a response and returns it as an absolute path.
+// Files that were not sent may only be created, never overwritten or deleted unseen.
+func resolveResponsePath(
+	req *codeRequest, relPath string, originals map[string]string, change fileChange,
+) (string, error) {
+	file, err := isValidFilePath(req.CurrentDir, filepath.Join(req.CurrentDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkNotProtected(req.CurrentDir, file); err != nil {
+		return "", err
+	}
+
+	if _, ok := originals[relPath]; ok {
+		return file, nil
+	}
+
+	if change.Delete {
+		return "", fmt.Errorf("%w: cannot delete %s", ErrUnexpectedFile, relPath)
+	}
+
+	// A file the replayed step created doesn't exist in the content being sent
+	if content, ok := req.Contents[file]; ok && content == nil {
+		return file, nil
+	}
+
+	if _, err := os.Lstat(file); err == nil {
+		return "", fmt.Errorf("%w: %s already exists", ErrUnexpectedFile, relPath)
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %v", ErrFailedToReadFile, err)
+	}
+
+	return file, nil
+}
+
+// checkNotProtected refuses files inside a .git directory, such as hooks that git would
+// run, and the session's own files.
+func checkNotProtected(currentDir, file string) error {
+	relPath, err := filepath.Rel(currentDir, file)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	if session.IsSessionPath(relPath) {
+		return fmt.Errorf("%w: %s", ErrProtectedFile, relPath)
+	}
+
+	for _, segment := range strings.Split(relPath, "/") {
+		if strings.EqualFold(segment, ".git") {
+			return fmt.Errorf("%w: %s", ErrProtectedFile, relPath)
+		}
+	}
+
+	return nil
+}
+
+// applyBatchResponse applies a multi-file response to the original contents, keyed by
+// relative path. Paths that were not sent are new files, and context files are refused.
+func applyBatchResponse(
+	format string, originals map[string]string, contextFiles []promptFile, response string,
+) (map[string]fileChange, error) {
+	updates := map[string]fileChange{}
+
+	for _, relPath := range edit.ParseDeletes(response) {
+		if err := checkNotContextFile(contextFiles, relPath); err != nil {
+			return nil, err
+		}
+
+		updates[relPath] = fileChange{Delete: true}
+	}
+
+	if format == EditFormatWhole {
+		sections, err := edit.ParseFileSections(response)
+		if err != nil && !(errors.Is(err, edit.ErrNoFileSections) && len(updates) > 0) {
+			return nil, fmt.Errorf("%w: %w", ErrEditNotApplied, err)
+		}
+
+		for relPath, section := range sections {
+			if err := checkNotContextFile(contextFiles, relPath); err != nil {
+				return nil, err
+			}
+
+			if updates[relPath].Delete {
+				return nil, fmt.Errorf("%w: %s is both edited and deleted", ErrEditNotApplied, relPath)
+			}
+
+			content, err := edit.ExtractCode(section, relPath)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrEditNotApplied, relPath, err)
+			}
+
+			updates[relPath] = fileChange{Content: content}
+		}
+
+		return updates, nil
+	}
+
+	fileBlocks, err := edit.ParseFileBlocks(response)
+	if err != nil && !(errors.Is(err, edit.ErrNoEditBlocks) && len(updates) > 0) {
+		return nil, fmt.Errorf("%w: %w", ErrEditNotApplied, err)
+	}
 
-	// Create a basic prompt
-	fullPrompt := fmt.Sprintf(
-		"Use the following prompt '%s' to modify the file contents and output the update code: \n%s",
-		prompt, fileContent)
+	for relPath, blocks := range fileBlocks {
+		if err := checkNotContextFile(contextFiles, relPath); err != nil {
+			return nil, err
+		}
+
+		if updates[relPath].Delete {
+			return nil, fmt.Errorf("%w: %s is both edited and deleted", ErrEditNotApplied, relPath)
+		}
+
+		content, err := edit.Apply(originals[relPath], blocks)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrEditNotApplied, relPath, err)
+		}
+
+		updates[relPath] = fileChange{Content: content}
+	}
+
+	return updates, nil
+}
+
+// applyResponse turns the llm response into the new content of the file.
+func applyResponse(format, path, content, response string) (string, error) {
+	if format == EditFormatWhole {
+		return edit.ExtractCode(response, path)
+	}
+
+	blocks, err := edit.ParseBlocks(response)
+	if err != nil {
+		return "", err
+	}
+
+	return edit.Apply(content, blocks)
+}
+
+// Use gollm to process the AI request.
+func processWithLLM(ctx context.Context, llm gollm.LLM, fullPrompt string) (string, error) {
 	promptValue := gollm.NewPrompt(fullPrompt)
 
 	// Generate a response
@@ -0,0 +1,389 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/llm"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+var errFakeLLM = errors.New("fake llm failure")
+
+// fakeLLM answers prompts with a function and only implements Generate.
+type fakeLLM struct {
+	gollm.LLM
+
+	mu       sync.Mutex
+	prompts  []string
+	generate func(ctx context.Context, prompt string) (string, error)
+}
+
+func (f *fakeLLM) Generate(ctx context.Context, prompt *gollm.Prompt, _ ...llm.GenerateOption) (string, error) {
+	f.mu.Lock()
+	f.prompts = append(f.prompts, prompt.Input)
+	f.mu.Unlock()
+
+	return f.generate(ctx, prompt.Input)
+}
+
+// setupFiles writes files with the given contents into a temp dir and returns their paths.
+func setupFiles(t *testing.T, contents map[string]string) (string, []string) {
+	dir := t.TempDir()
+	paths := []string{}
+
+	for _, name := range []string{"a.go", "b.go", "c.go", "d.go"} {
+		content, ok := contents[name]
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		assert.NilError(t, os.WriteFile(path, []byte(content), 0600))
+		paths = append(paths, path)
+	}
+
+	return dir, paths
+}
+
+// TestModifyCode_PerFileParallel verifies each file gets its own result regardless of scheduling.
+func TestModifyCode_PerFileParallel(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{
+		"a.go": "package a\n", "b.go": "package b\n", "c.go": "package c\n", "d.go": "package d\n",
+	})
+
+	var inFlight, maxInFlight atomic.Int32
+
+	fake := &fakeLLM{generate: func(_ context.Context, prompt string) (string, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		pkg := prompt[strings.LastIndex(prompt, "package ")+len("package "):]
+		pkg = strings.TrimSpace(pkg)
+
+		return "<<<<<<< SEARCH\npackage " + pkg + "\n=======\npackage " + pkg + "_new\n>>>>>>> REPLACE\n", nil
+	}}
+
+	modifications, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "rename", AbsFilePaths: paths,
+		EditFormat: cmd.EditFormatSearchReplace, PerFile: true, Jobs: 2,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, maxInFlight.Load() <= 2, "Expected at most 2 concurrent calls, got %d", maxInFlight.Load())
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		assert.Assert(t, cmp.Equal(modifications[filepath.Join(dir, name+".go")].Content, "package "+name+"_new\n"))
+	}
+}
+
+// TestModifyCode_AggregatesErrors verifies a failing file is reported and stops the remaining work.
+func TestModifyCode_AggregatesErrors(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n", "c.go": "package c\n"})
+
+	fake := &fakeLLM{generate: func(ctx context.Context, prompt string) (string, error) {
+		if strings.Contains(prompt, "editing the file a.go") {
+			return "", errFakeLLM
+		}
+
+		<-ctx.Done()
+
+		return "", ctx.Err()
+	}}
+
+	_, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "rename", AbsFilePaths: paths,
+		EditFormat: cmd.EditFormatSearchReplace, PerFile: true, Jobs: 3,
+	})
+	assert.Assert(t, errors.Is(err, errFakeLLM), "Expected errFakeLLM, got: %v", err)
+	assert.Assert(t, !errors.Is(err, context.Canceled), "Cancelled files should not be reported: %v", err)
+	assert.Assert(t, cmp.Contains(err.Error(), "a.go"))
+}
+
+// TestModifyCode_Batch verifies all files go out in one prompt and the response is split per file.
+func TestModifyCode_Batch(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{
+		"a.go": "package a\n\ntype Old struct{}\n", "b.go": "package a\n\nvar x Old\n", "c.go": "package a\n",
+	})
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "FILE: a.go\n<<<<<<< SEARCH\ntype Old struct{}\n=======\ntype New struct{}\n>>>>>>> REPLACE\n" +
+			"FILE: b.go\n<<<<<<< SEARCH\nvar x Old\n=======\nvar x New\n>>>>>>> REPLACE\n", nil
+	}}
+
+	modifications, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "rename Old to New", AbsFilePaths: paths, EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(fake.prompts, 1))
+	assert.Assert(t, cmp.Contains(fake.prompts[0], "=== FILE: c.go ==="))
+
+	assert.DeepEqual(t, modifications, map[string]cmd.FileChange{
+		paths[0]: {Content: "package a\n\ntype New struct{}\n"},
+		paths[1]: {Content: "package a\n\nvar x New\n"},
+		paths[2]: {Content: "package a\n"},
+	})
+}
+
+// TestModifyCode_BatchOutsideDir ensures the response cannot write outside the project.
+func TestModifyCode_BatchOutsideDir(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "FILE: ../etc/passwd\n<<<<<<< SEARCH\n=======\nroot\n>>>>>>> REPLACE\n", nil
+	}}
+
+	_, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "x", AbsFilePaths: paths, EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.Assert(t, errors.Is(err, cmd.ErrFileOutsideCurrentDir), "Expected ErrFileOutsideCurrentDir, got: %v", err)
+}
+
+// TestModifyCode_BatchProtectedPaths ensures the response cannot create files inside .git,
+// such as hooks, or inside the session history.
+func TestModifyCode_BatchProtectedPaths(t *testing.T) {
+	protected := []string{".git/hooks/pre-commit", "sub/.git/config", ".ca_sessions/blobs/x", ".ca_session.json"}
+
+	for _, relPath := range protected {
+		t.Run(relPath, func(t *testing.T) {
+			dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n"})
+
+			fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+				return "FILE: " + relPath + "\n<<<<<<< SEARCH\n=======\n#!/bin/sh\n>>>>>>> REPLACE\n", nil
+			}}
+
+			_, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+				CurrentDir: dir, Prompt: "x", AbsFilePaths: paths, EditFormat: cmd.EditFormatSearchReplace,
+			})
+			assert.Assert(t, errors.Is(err, cmd.ErrProtectedFile), "Expected ErrProtectedFile, got: %v", err)
+		})
+	}
+}
+
+// TestModifyCode_CreateAndDelete verifies a response can split a file into a new one and delete files.
+func TestModifyCode_CreateAndDelete(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{
+		"a.go": "package a\n\nfunc A() {}\nfunc B() {}\n", "b.go": "package a\n",
+	})
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "FILE: a.go\n<<<<<<< SEARCH\nfunc B() {}\n=======\n>>>>>>> REPLACE\n" +
+			"FILE: sub/b.go\n<<<<<<< SEARCH\n=======\npackage a\n\nfunc B() {}\n>>>>>>> REPLACE\n" +
+			"DELETE: b.go\n", nil
+	}}
+
+	modifications, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "split", AbsFilePaths: paths, EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, modifications, map[string]cmd.FileChange{
+		paths[0]:                          {Content: "package a\n\nfunc A() {}\n"},
+		paths[1]:                          {Delete: true},
+		filepath.Join(dir, "sub", "b.go"): {Content: "package a\n\nfunc B() {}\n"},
+	})
+}
+
+// TestModifyCode_EditedAndDeleted ensures a file can't be both edited and deleted, in
+// either edit format.
+func TestModifyCode_EditedAndDeleted(t *testing.T) {
+	responses := map[string]string{
+		cmd.EditFormatSearchReplace: "FILE: a.go\n<<<<<<< SEARCH\npackage a\n=======\npackage b\n>>>>>>> REPLACE\n",
+		cmd.EditFormatWhole:         "=== FILE: a.go ===\npackage b\n=== END FILE ===\n",
+	}
+
+	for format, response := range responses {
+		t.Run(format, func(t *testing.T) {
+			dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package a\n"})
+
+			fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+				return response + "DELETE: a.go\n", nil
+			}}
+
+			_, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+				CurrentDir: dir, Prompt: "x", AbsFilePaths: paths, EditFormat: format,
+			})
+			assert.Assert(t, errors.Is(err, cmd.ErrEditNotApplied), "Expected ErrEditNotApplied, got: %v", err)
+			assert.Assert(t, cmp.Contains(err.Error(), "both edited and deleted"))
+		})
+	}
+}
+
+// TestModifyCode_RejectsUnseenFiles ensures existing files that were not sent cannot be overwritten or deleted.
+func TestModifyCode_RejectsUnseenFiles(t *testing.T) {
+	for _, response := range []string{
+		"FILE: c.go\n<<<<<<< SEARCH\n=======\npackage c\n>>>>>>> REPLACE\n",
+		"DELETE: c.go\n",
+	} {
+		dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "c.go": "package c\n"})
+
+		fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) { return response, nil }}
+
+		_, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+			CurrentDir: dir, Prompt: "x", AbsFilePaths: paths[:1], EditFormat: cmd.EditFormatSearchReplace,
+		})
+		assert.Assert(t, errors.Is(err, cmd.ErrUnexpectedFile), "Expected ErrUnexpectedFile, got: %v", err)
+	}
+}
+
+// TestModifyCode_ContextFiles verifies context files are sent as reference but can never be edited.
+func TestModifyCode_ContextFiles(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package a\n\ntype Store interface{}\n"})
+
+	fake := &fakeLLM{generate: func(_ context.Context, prompt string) (string, error) {
+		assert.Assert(t, cmp.Contains(prompt, "Do NOT change"))
+		assert.Assert(t, cmp.Contains(prompt, "type Store interface{}"))
+
+		return "FILE: b.go\n<<<<<<< SEARCH\ntype Store interface{}\n=======\ntype Store any\n>>>>>>> REPLACE\n", nil
+	}}
+
+	for _, perFile := range []bool{false, true} {
+		modifications, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+			CurrentDir: dir, Prompt: "x", AbsFilePaths: paths[:1], ContextFiles: paths[1:],
+			EditFormat: cmd.EditFormatSearchReplace, PerFile: perFile,
+		})
+
+		if !perFile {
+			assert.Assert(t, errors.Is(err, cmd.ErrUnexpectedFile), "Expected ErrUnexpectedFile, got: %v", err)
+			continue
+		}
+
+		// Per-file responses only ever apply to the file being edited
+		assert.Assert(t, errors.Is(err, cmd.ErrEditNotApplied), "Expected ErrEditNotApplied, got: %v", err)
+		assert.Assert(t, cmp.Len(modifications, 0))
+	}
+}
+
+// TestModifyCode_RepoMap verifies symbols from other files are summarized in the prompt.
+func TestModifyCode_RepoMap(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{
+		"a.go": "package a\n", "b.go": "package a\n\nfunc Helper(n int) string\n",
+	})
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "FILE: a.go\n<<<<<<< SEARCH\npackage a\n=======\npackage a // uses Helper\n>>>>>>> REPLACE\n", nil
+	}}
+
+	_, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "call Helper", AbsFilePaths: paths[:1],
+		EditFormat: cmd.EditFormatSearchReplace, RepoMapTokens: 100,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(fake.prompts[0], "Symbols defined elsewhere in the project"))
+	assert.Assert(t, cmp.Contains(fake.prompts[0], "func Helper(n int) string // b.go:3"))
+}
+
+// TestModifyCode_PromptTooLarge verifies prompts that leave no room for the response are not sent.
+func TestModifyCode_PromptTooLarge(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n\n" + strings.Repeat("var x = 1\n", 200)})
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "", errFakeLLM
+	}}
+
+	req := &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "x", AbsFilePaths: paths, EditFormat: cmd.EditFormatSearchReplace,
+		Budget: &cmd.TokenBudget{ContextTokens: 600, MaxOutputTokens: 200},
+	}
+
+	_, err := cmd.ModifyCode(context.Background(), fake, req)
+	assert.Assert(t, errors.Is(err, cmd.ErrPromptTooLarge), "Expected ErrPromptTooLarge, got: %v", err)
+	assert.Assert(t, cmp.Len(fake.prompts, 0), "The llm should not be called.")
+
+	req.Budget.IgnoreLimit = true
+	_, err = cmd.ModifyCode(context.Background(), fake, req)
+	assert.Assert(t, errors.Is(err, errFakeLLM), "Expected the prompt to be sent, got: %v", err)
+}
+
+// TestModifyCode_Chunked verifies large files are edited only in the chunks the llm selects.
+func TestModifyCode_Chunked(t *testing.T) {
+	source := "package a\n\n" + "func A() int {\n\treturn 1\n}\n\n" + "func B() int {\n\treturn 2\n}\n"
+	dir, paths := setupFiles(t, map[string]string{"a.go": source, "b.go": "package a\n"})
+
+	fake := &fakeLLM{generate: func(_ context.Context, prompt string) (string, error) {
+		switch {
+		case strings.Contains(prompt, "too large to edit at once"):
+			return "2", nil
+		case strings.Contains(prompt, "You are editing section 2"):
+			assert.Assert(t, !strings.Contains(prompt, "return 1"), "Other sections should not be sent.")
+			return "<<<<<<< SEARCH\n\treturn 2\n=======\n\treturn 20\n>>>>>>> REPLACE\n", nil
+		default:
+			return "FILE: b.go\n<<<<<<< SEARCH\npackage a\n=======\npackage a // small\n>>>>>>> REPLACE\n", nil
+		}
+	}}
+
+	modifications, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "change B", AbsFilePaths: paths, EditFormat: cmd.EditFormatSearchReplace,
+		ChunkTokens: 10,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, modifications[paths[0]].Content, strings.Replace(source, "return 2", "return 20", 1))
+	assert.Equal(t, modifications[paths[1]].Content, "package a // small\n")
+	assert.Assert(t, cmp.Len(fake.prompts, 3))
+}
+
+// TestParseChunkSelection verifies only plain lists of section numbers, or none, are trusted.
+func TestParseChunkSelection(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  []int
+		ok    bool
+	}{
+		{"2", []int{1}, true},
+		{"3, 1,2", []int{0, 1, 2}, true},
+		{" 2.\n", []int{1}, true},
+		{"none", []int{}, true},
+		{"None.", []int{}, true},
+		{"Section 2 (lines 10-14) needs 3 changes", nil, false},
+		{"2, 3 and none of the others", nil, false},
+		{"none of the sections look relevant, maybe 2", nil, false},
+		{"2, 12", nil, false},
+		{"0", nil, false},
+		{"", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := cmd.ParseChunkSelection(tt.reply, 3)
+		assert.Equal(t, ok, tt.ok, "reply %q", tt.reply)
+		assert.DeepEqual(t, got, tt.want)
+	}
+}
+
+// TestModifyCode_Symbol verifies only the targeted declaration is sent and spliced back.
+func TestModifyCode_Symbol(t *testing.T) {
+	source := "package a\n\n// A returns one.\nfunc A() int {\n\treturn 1\n}\n\nfunc  B()   int { return 2 }\n"
+	dir, paths := setupFiles(t, map[string]string{"a.go": source})
+
+	fake := &fakeLLM{generate: func(_ context.Context, prompt string) (string, error) {
+		assert.Assert(t, cmp.Contains(prompt, "Current declaration of a.A:\n// A returns one.\nfunc A() int {"))
+		assert.Assert(t, !strings.Contains(prompt, "return 2"), "Other declarations should not be sent.")
+
+		return "```go\n// A returns ten.\nfunc A() int {\n\treturn 10\n}\n```", nil
+	}}
+
+	modifications, err := cmd.ModifyCode(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "return ten", AbsFilePaths: paths, EditFormat: cmd.EditFormatWhole,
+		Symbols: []cmd.SymbolTarget{{Symbol: "a.A", File: paths[0]}},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, modifications[paths[0]].Content,
+		"package a\n\n// A returns ten.\nfunc A() int {\n\treturn 10\n}\n\nfunc  B()   int { return 2 }\n")
+}
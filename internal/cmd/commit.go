@@ -0,0 +1,265 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/git"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"github.com/teilomillet/gollm"
+	cli "github.com/urfave/cli/v2"
+)
+
+// Commit errors.
+var (
+	ErrNotGitRepository   = errors.New("project is not a git repository")
+	ErrNothingToCommit    = errors.New("no changes from session steps to commit")
+	ErrEmptyCommitMessage = errors.New("commit message is empty")
+	ErrFailedToCommit     = errors.New("failed to commit changes")
+)
+
+// Diffs beyond this many bytes are cut short in the commit message prompt.
+const maxCommitDiff = 16000
+
+// CommitCommand commits the files changed by the session steps since the last commit.
+func CommitCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "commit",
+		Usage:     "Commit the files changed by session steps, generating a message when none is given",
+		ArgsUsage: "[message]",
+		Action: func(c *cli.Context) error {
+			currentDir, _, err := findCurrentDir()
+			if err != nil {
+				return err
+			}
+
+			message := c.Args().First()
+
+			// The llm is only needed to write the message
+			var llm gollm.LLM
+
+			if message == "" {
+				llmConfig := NewLLMConfigFromContext(c)
+				if err := llmConfig.Validate(); err != nil {
+					return err
+				}
+
+				llm, err = llmConfig.BuildLLM()
+				if err != nil {
+					return err
+				}
+			}
+
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			return executeCommitCommand(ctx, llm, currentDir, message)
+		},
+	}
+}
+
+func executeCommitCommand(ctx context.Context, llm gollm.LLM, currentDir, message string) error {
+	currentSession, err := session.LoadCurrentSession(currentDir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToLoadSession, err)
+	}
+
+	return commitSteps(ctx, llm, currentDir, currentSession, message)
+}
+
+// commitSteps stages and commits exactly the files changed by the steps since the last
+// commit, asking the llm for a message when none is given, and records the commit on
+// those steps.
+func commitSteps(
+	ctx context.Context, llm gollm.LLM, currentDir string, currentSession *session.Session, message string,
+) error {
+	if !git.IsRepository(currentDir) {
+		return ErrNotGitRepository
+	}
+
+	steps := uncommittedSteps(currentSession)
+
+	files, err := changedStepFiles(currentDir, steps)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return ErrNothingToCommit
+	}
+
+	commit, message, err := stageAndCommit(ctx, llm, currentDir, steps, files, message)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		step.Git.Post.Commit = commit
+	}
+
+	if err := session.SaveCurrentSession(currentDir, currentSession); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToSaveSession, err)
+	}
+
+	fmt.Printf("✅ Committed %d files as %s: %s\n", len(files), shortHash(commit), firstLine(message))
+
+	return nil
+}
+
+// stageAndCommit stages the files and commits them, generating the message when it is
+// empty, and returns the commit and its message. The index is restored when the commit
+// isn't made, so a failed message leaves anything staged beforehand as it was.
+func stageAndCommit(ctx context.Context, llm gollm.LLM, currentDir string, steps []*session.Step,
+	files []string, message string) (commit, commitMessage string, err error) {
+	index, err := git.SaveIndex(currentDir)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrFailedToCommit, err)
+	}
+
+	if err := git.Add(currentDir, files...); err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrFailedToCommit, err)
+	}
+
+	defer func() {
+		if err != nil {
+			if restoreErr := git.RestoreIndex(currentDir, index); restoreErr != nil {
+				err = errors.Join(err, fmt.Errorf("%w: %v", ErrFailedToCommit, restoreErr))
+			}
+		}
+	}()
+
+	if message == "" {
+		message, err = generateCommitMessage(ctx, llm, currentDir, steps, files)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	commit, err = git.Commit(currentDir, message, files...)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrFailedToCommit, err)
+	}
+
+	return commit, message, nil
+}
+
+// uncommittedSteps returns the steps after the last one committed by ca commit. ca
+// doesn't otherwise move HEAD, so a committed step is one whose post commit differs
+// from its pre commit.
+func uncommittedSteps(currentSession *session.Session) []*session.Step {
+	start := 0
+
+	for i, step := range currentSession.Steps {
+		if step.Git.Post.Commit != step.Git.Pre.Commit {
+			start = i + 1
+		}
+	}
+
+	return currentSession.Steps[start:]
+}
+
+// changedStepFiles returns the files touched by the steps that git still reports as
+// changed, relative to currentDir.
+func changedStepFiles(currentDir string, steps []*session.Step) ([]string, error) {
+	seen := map[string]bool{}
+	paths := []string{}
+
+	for _, step := range steps {
+		for _, snapshot := range step.CombinedSnapshots() {
+			if !seen[snapshot.Path] {
+				seen[snapshot.Path] = true
+				paths = append(paths, snapshot.Path)
+			}
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	statuses, err := git.Status(currentDir, paths...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToCommit, err)
+	}
+
+	files := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		files = append(files, status.Path)
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// generateCommitMessage asks the llm for a conventional commit message describing the
+// staged changes and the prompts that made them.
+func generateCommitMessage(
+	ctx context.Context, llm gollm.LLM, currentDir string, steps []*session.Step, files []string,
+) (string, error) {
+	diff, err := git.StagedDiff(currentDir, files...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFailedToCommit, err)
+	}
+
+	if len(diff) > maxCommitDiff {
+		diff = diff[:maxCommitDiff] + "\n..."
+	}
+
+	prompts := make([]string, 0, len(steps))
+	for _, step := range steps {
+		if step.Type != session.StepTypeSummary {
+			prompts = append(prompts, step.Command.Prompt)
+		}
+	}
+
+	response, err := processWithLLM(ctx, llm, buildCommitMessagePrompt(prompts, diff))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrAIProcessingFailed, err)
+	}
+
+	message := cleanCommitMessage(response)
+	if message == "" {
+		return "", ErrEmptyCommitMessage
+	}
+
+	return message, nil
+}
+
+// cleanCommitMessage strips code fences and surrounding blank lines from a generated message.
+func cleanCommitMessage(response string) string {
+	lines := strings.Split(strings.TrimSpace(response), "\n")
+	kept := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			kept = append(kept, strings.TrimRight(line, " \t"))
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// shortHash abbreviates a commit hash for display.
+func shortHash(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+
+	return commit
+}
+
+// firstLine returns the subject line of a message.
+func firstLine(message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	return subject
+}
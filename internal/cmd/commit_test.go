@@ -0,0 +1,172 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// gitOutput runs a git command in dir and returns its trimmed output.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	// nolint:gosec // Why: test code
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	assert.NilError(t, err, string(out))
+
+	return strings.TrimSpace(string(out))
+}
+
+// initRepo creates a git repository with committed main.go and other.go.
+func initRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	gitOutput(t, dir, "init", "-q")
+	gitOutput(t, dir, "config", "user.name", "test")
+	gitOutput(t, dir, "config", "user.email", "test@example.com")
+
+	for _, name := range []string{"main.go", "other.go"} {
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, name), []byte("package main\n"), 0600))
+	}
+
+	gitOutput(t, dir, "add", ".")
+	gitOutput(t, dir, "commit", "-q", "-m", "initial")
+
+	return dir
+}
+
+// setupRepoSession creates a git repository and starts a session in it.
+func setupRepoSession(t *testing.T) string {
+	dir := initRepo(t)
+	assert.NilError(t, session.StartSession(&session.StartSessionRequest{Name: "commit", Dir: dir}))
+
+	return dir
+}
+
+// commitLLM edits main.go and answers commit message prompts with message.
+func commitLLM(message string) *fakeLLM {
+	return &fakeLLM{generate: func(_ context.Context, prompt string) (string, error) {
+		if strings.Contains(prompt, "Write a git commit message") {
+			return "```\n" + message + "\n```", nil
+		}
+
+		return "FILE: main.go\n<<<<<<< SEARCH\npackage main\n=======\npackage main\n\nfunc main() {}\n>>>>>>> REPLACE\n", nil
+	}}
+}
+
+// TestCode_Commit verifies --commit commits only the step's files with a generated message
+// and records the commit on the step.
+func TestCode_Commit(t *testing.T) {
+	dir := setupRepoSession(t)
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package other\n"), 0600))
+
+	fake := commitLLM("feat: add main function")
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "add main", AbsFilePaths: []string{filepath.Join(dir, "main.go")},
+		EditFormat: cmd.EditFormatSearchReplace, Commit: true,
+	})
+	assert.NilError(t, err)
+
+	assert.Assert(t, cmp.Len(fake.prompts, 2))
+	assert.Assert(t, cmp.Contains(fake.prompts[1], "- add main"))
+	assert.Assert(t, cmp.Contains(fake.prompts[1], "+func main() {}"))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "log", "-1", "--format=%s"), "feat: add main function"))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "show", "--name-only", "--format=", "HEAD"), "main.go"))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "status", "--porcelain", "--", "other.go"), "M other.go"))
+
+	currentSession, err := session.LoadCurrentSession(dir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Equal(currentSession.Steps[0].Git.Post.Commit, gitOutput(t, dir, "rev-parse", "HEAD")))
+
+	err = cmd.ExecuteCommitCommand(context.Background(), fake, dir, "")
+	assert.Assert(t, errors.Is(err, cmd.ErrNothingToCommit), "Expected ErrNothingToCommit, got: %v", err)
+}
+
+// TestCommit_Message verifies a given message is used without asking the llm.
+func TestCommit_Message(t *testing.T) {
+	dir := setupRepoSession(t)
+
+	err := cmd.ExecuteCodeCommand(context.Background(), commitLLM(""), &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "add main", AbsFilePaths: []string{filepath.Join(dir, "main.go")},
+		EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+
+	assert.NilError(t, cmd.ExecuteCommitCommand(context.Background(), nil, dir, "fix: add main"))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "log", "-1", "--format=%s"), "fix: add main"))
+}
+
+// TestCommit_FailedMessageUnstages verifies the index is left as it was when no message
+// can be generated.
+func TestCommit_FailedMessageUnstages(t *testing.T) {
+	dir := setupRepoSession(t)
+	head := gitOutput(t, dir, "rev-parse", "HEAD")
+	fake := commitLLM("")
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "add main", AbsFilePaths: []string{filepath.Join(dir, "main.go")},
+		EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+
+	err = cmd.ExecuteCommitCommand(context.Background(), fake, dir, "")
+	assert.Assert(t, errors.Is(err, cmd.ErrEmptyCommitMessage), "Expected ErrEmptyCommitMessage, got: %v", err)
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "diff", "--cached", "--name-only"), ""))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "diff", "--name-only"), "main.go"))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "rev-parse", "HEAD"), head))
+}
+
+// TestCommit_FailedMessageKeepsStaged verifies changes the user staged before the commit
+// are still staged when it fails.
+func TestCommit_FailedMessageKeepsStaged(t *testing.T) {
+	dir := setupRepoSession(t)
+	fake := commitLLM("")
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "add main", AbsFilePaths: []string{filepath.Join(dir, "main.go")},
+		EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package other\n"), 0600))
+	gitOutput(t, dir, "add", "main.go", "other.go")
+
+	err = cmd.ExecuteCommitCommand(context.Background(), fake, dir, "")
+	assert.Assert(t, errors.Is(err, cmd.ErrEmptyCommitMessage), "Expected ErrEmptyCommitMessage, got: %v", err)
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "diff", "--cached", "--name-only"), "main.go\nother.go"))
+}
+
+// TestCode_GitStateLeavesOutSession verifies the recorded dirty files never include the
+// session file or its blob store, however many steps run.
+func TestCode_GitStateLeavesOutSession(t *testing.T) {
+	dir := setupRepoSession(t)
+	fake := wholeFileLLM()
+
+	assert.NilError(t, cmd.ExecuteCodeCommand(context.Background(), fake, editRequest(dir, "main.go")))
+	assert.NilError(t, cmd.ExecuteCodeCommand(context.Background(), fake, editRequest(dir, "main.go")))
+
+	currentSession, err := session.LoadCurrentSession(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(currentSession.Steps), 2)
+
+	for _, step := range currentSession.Steps {
+		assert.DeepEqual(t, step.Git.Post.UncommittedFiles, []string{"main.go"})
+	}
+
+	assert.DeepEqual(t, currentSession.Steps[1].Git.Pre.UncommittedFiles, []string{"main.go"})
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ANSI colors used when rendering diffs to a terminal.
+const (
+	colorReset = "\033[0m"
+	colorBold  = "\033[1m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+)
+
+// Number of unchanged lines shown around each change.
+const diffContextLines = 3
+
+// unifiedDiff renders the unified diff between two versions of a file.
+// Missing versions are shown against /dev/null like git does.
+func unifiedDiff(path string, before, after []byte, beforeExists, afterExists bool) (string, error) {
+	fromFile, toFile := "a/"+path, "b/"+path
+	if !beforeExists {
+		fromFile = "/dev/null"
+	}
+
+	if !afterExists {
+		toFile = "/dev/null"
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitDiffLines(before),
+		B:        splitDiffLines(after),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  diffContextLines,
+	})
+}
+
+// Marker git adds after a last line that has no line ending.
+const noNewlineMarker = "\\ No newline at end of file\n"
+
+// splitDiffLines splits content into lines that keep their line endings. A last line
+// without one gets git's marker, so the diff shows it and stays line oriented.
+func splitDiffLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+
+	// Content ending in a line ending, or empty content, leaves an empty last element
+	last := len(lines) - 1
+	if lines[last] == "" {
+		return lines[:last]
+	}
+
+	lines[last] += "\n" + noNewlineMarker
+
+	return lines
+}
+
+// snapshotDiff renders the diff recorded by a snapshot using the blob store.
+func snapshotDiff(blobStore *session.BlobStore, snapshot session.FileSnapshot) (string, error) {
+	before, err := snapshotContent(blobStore, snapshot.PreHash)
+	if err != nil {
+		return "", err
+	}
+
+	after, err := snapshotContent(blobStore, snapshot.PostHash)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff(snapshot.Path, before, after, snapshot.PreHash != "", snapshot.PostHash != "")
+}
+
+// snapshotContent returns the stored content for a hash, treating an empty hash as an absent file.
+func snapshotContent(blobStore *session.BlobStore, hash string) ([]byte, error) {
+	if hash == "" {
+		return nil, nil
+	}
+
+	return blobStore.Get(hash)
+}
+
+// diffStat counts the added and removed lines of a unified diff. Only the lines before
+// the first hunk are file headers; inside a hunk "---" is a removed line starting "--".
+func diffStat(diff string) (added, removed int) {
+	inHunk := false
+
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk:
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+
+	return added, removed
+}
+
+// colorizeDiff adds terminal colors to the lines of a unified diff.
+func colorizeDiff(diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	inHunk := false
+
+	var b strings.Builder
+
+	for _, line := range lines {
+		color := ""
+
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			color, inHunk = colorCyan, true
+		case !inHunk && (strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---")):
+			color = colorBold
+		case strings.HasPrefix(line, "+"):
+			color = colorGreen
+		case strings.HasPrefix(line, "-"):
+			color = colorRed
+		}
+
+		if color == "" || line == "" {
+			b.WriteString(line)
+			continue
+		}
+
+		text := strings.TrimSuffix(line, "\n")
+		fmt.Fprintf(&b, "%s%s%s%s", color, text, colorReset, line[len(text):])
+	}
+
+	return b.String()
+}
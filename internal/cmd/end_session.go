@@ -5,9 +5,6 @@
 package cmd
 
 import (
-	"os"
-
-	"github.com/chrisrob11/codeassistant/internal/session"
 	cli "github.com/urfave/cli/v2"
 )
 
@@ -16,13 +13,20 @@ func EndSessionCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "end-session",
 		Usage: "Archive session to historical storage",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "finish",
+				Value: FinishKeep,
+				Usage: "What to do with the session branch: squash, rebase, discard or keep",
+			},
+		},
 		Action: func(c *cli.Context) error {
-			currentDir, err := os.Getwd()
+			currentDir, _, err := findCurrentDir()
 			if err != nil {
 				return err
 			}
 
-			return session.EndSession(currentDir)
+			return executeEndSession(currentDir, c.String("finish"))
 		},
 	}
 }
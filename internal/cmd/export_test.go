@@ -0,0 +1,31 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+// Exported aliases so the external test package can reach unexported helpers.
+var (
+	ExecuteRollbackCommand = executeRollbackCommand
+	ExecuteReviewCommand   = executeReviewCommand
+	ModifyCode             = modifyCode
+	ExecuteCodeCommand     = executeCodeCommand
+	ReplayStep             = replayStep
+	ReplayCodeRequest      = replayCodeRequest
+	ExecuteCommitCommand   = executeCommitCommand
+	StartIsolatedSession   = startIsolatedSession
+	ExecuteEndSession      = executeEndSession
+	IsValidFilePath        = isValidFilePath
+	ParseChunkSelection    = parseChunkSelection
+)
+
+// Request aliases exposed to tests.
+type (
+	RollbackRequest  = rollbackRequest
+	ReviewRequest    = reviewRequest
+	CodeRequest      = codeRequest
+	FileChange       = fileChange
+	TokenBudget      = tokenBudget
+	SymbolTarget     = symbolTarget
+	IsolationRequest = isolationRequest
+)
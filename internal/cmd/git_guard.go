@@ -0,0 +1,115 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/git"
+	"github.com/chrisrob11/codeassistant/internal/session"
+)
+
+// Git guard errors.
+var (
+	ErrUntrackedFiles = errors.New("files are not tracked by git, pass --allow-untracked to modify them")
+	ErrDirtyFiles     = errors.New("files have uncommitted changes, pass --allow-dirty to modify them")
+)
+
+// checkFilesClean refuses to modify files that are untracked, ignored or have uncommitted changes
+// that weren't made by the session, so AI and human edits never share a diff. Files whose
+// content is what an earlier step left are fine. Projects outside git aren't checked.
+func checkFilesClean(currentSession *session.Session, req *codeRequest) error {
+	if (req.AllowDirty && req.AllowUntracked) || !git.IsRepository(req.CurrentDir) {
+		return nil
+	}
+
+	relPaths := make([]string, 0, len(req.AbsFilePaths))
+
+	for _, file := range req.AbsFilePaths {
+		relPath, err := filepath.Rel(req.CurrentDir, file)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+		}
+
+		relPaths = append(relPaths, relPath)
+	}
+
+	// Ignored files are untracked too, but plain git status leaves them out
+	statuses, err := git.StatusWithIgnored(req.CurrentDir, relPaths...)
+	if err != nil {
+		return err
+	}
+
+	untracked, dirty := humanChanges(req, statuses, sessionPostHashes(currentSession))
+	errs := []error{}
+
+	if len(untracked) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrUntrackedFiles, strings.Join(untracked, ", ")))
+	}
+
+	if len(dirty) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrDirtyFiles, strings.Join(dirty, ", ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// humanChanges splits the changed files that weren't allowed or left by the session into
+// untracked and dirty ones.
+func humanChanges(
+	req *codeRequest, statuses []git.FileStatus, stepContent map[string]string,
+) (untracked, dirty []string) {
+	for _, status := range statuses {
+		allowed := req.AllowDirty
+		if status.Untracked() {
+			allowed = req.AllowUntracked
+		}
+
+		if allowed || madeBySession(req.CurrentDir, status.Path, stepContent) {
+			continue
+		}
+
+		if status.Untracked() {
+			untracked = append(untracked, status.Path)
+		} else {
+			dirty = append(dirty, status.Path)
+		}
+	}
+
+	return untracked, dirty
+}
+
+// sessionPostHashes returns the content hash each step of the session last left for a file.
+func sessionPostHashes(currentSession *session.Session) map[string]string {
+	hashes := map[string]string{}
+
+	for _, step := range currentSession.Steps {
+		for _, snapshot := range step.CombinedSnapshots() {
+			hashes[snapshot.Path] = snapshot.PostHash
+		}
+	}
+
+	return hashes
+}
+
+// madeBySession reports whether the file still has the content the session last wrote to it.
+func madeBySession(currentDir, relPath string, stepContent map[string]string) bool {
+	postHash, ok := stepContent[relPath]
+	if !ok || postHash == "" {
+		return false
+	}
+
+	// nolint:gosec //Why: files are validated within a specific path
+	content, err := os.ReadFile(filepath.Join(currentDir, relPath))
+	if err != nil {
+		return false
+	}
+
+	return session.HashContent(content) == postHash
+}
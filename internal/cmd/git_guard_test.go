@@ -0,0 +1,133 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// editRequest asks for an edit of the named files in dir.
+func editRequest(dir string, names ...string) *cmd.CodeRequest {
+	paths := []string{}
+	for _, name := range names {
+		paths = append(paths, filepath.Join(dir, name))
+	}
+
+	return &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "add a function", AbsFilePaths: paths, EditFormat: cmd.EditFormatWhole, PerFile: true,
+	}
+}
+
+// wholeFileLLM answers every prompt with a new version of the file.
+func wholeFileLLM() *fakeLLM {
+	var calls atomic.Int32
+
+	return &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return fmt.Sprintf("package main\n\nfunc f%d() {}\n", calls.Add(1)), nil
+	}}
+}
+
+// TestGitGuard_RefusesHumanChanges verifies dirty and untracked files are refused unless allowed.
+func TestGitGuard_RefusesHumanChanges(t *testing.T) {
+	dir := setupRepoSession(t)
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n// edited\n"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0600))
+
+	fake := wholeFileLLM()
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, editRequest(dir, "main.go", "new.go"))
+	assert.Assert(t, errors.Is(err, cmd.ErrDirtyFiles), "Expected ErrDirtyFiles, got: %v", err)
+	assert.Assert(t, errors.Is(err, cmd.ErrUntrackedFiles), "Expected ErrUntrackedFiles, got: %v", err)
+	assert.Assert(t, cmp.Len(fake.prompts, 0))
+
+	req := editRequest(dir, "new.go")
+	req.AllowUntracked = true
+	assert.NilError(t, cmd.ExecuteCodeCommand(context.Background(), fake, req))
+
+	req = editRequest(dir, "main.go")
+	req.AllowUntracked = true
+	err = cmd.ExecuteCodeCommand(context.Background(), fake, req)
+	assert.Assert(t, errors.Is(err, cmd.ErrDirtyFiles), "Expected ErrDirtyFiles, got: %v", err)
+
+	req.AllowDirty = true
+	assert.NilError(t, cmd.ExecuteCodeCommand(context.Background(), fake, req))
+}
+
+// TestGitGuard_RefusesIgnoredFiles verifies files git ignores count as untracked, even
+// though git status leaves them out by default.
+func TestGitGuard_RefusesIgnoredFiles(t *testing.T) {
+	dir := setupRepoSession(t)
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("local.go\n"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "local.go"), []byte("package main\n"), 0600))
+
+	fake := wholeFileLLM()
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, editRequest(dir, "local.go"))
+	assert.Assert(t, errors.Is(err, cmd.ErrUntrackedFiles), "Expected ErrUntrackedFiles, got: %v", err)
+	assert.Assert(t, cmp.Len(fake.prompts, 0))
+
+	req := editRequest(dir, "local.go")
+	req.AllowUntracked = true
+	assert.NilError(t, cmd.ExecuteCodeCommand(context.Background(), fake, req))
+}
+
+// TestGitGuard_AllowsSessionChanges verifies files left dirty by earlier steps can be
+// modified again until a human edits them.
+func TestGitGuard_AllowsSessionChanges(t *testing.T) {
+	dir := setupRepoSession(t)
+	fake := wholeFileLLM()
+
+	assert.NilError(t, cmd.ExecuteCodeCommand(context.Background(), fake, editRequest(dir, "main.go")))
+	assert.NilError(t, cmd.ExecuteCodeCommand(context.Background(), fake, editRequest(dir, "main.go")))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "main.go")), "package main\n\nfunc f2() {}\n"))
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n// human\n"), 0600))
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, editRequest(dir, "main.go"))
+	assert.Assert(t, errors.Is(err, cmd.ErrDirtyFiles), "Expected ErrDirtyFiles, got: %v", err)
+}
+
+// TestGitGuard_Replay verifies a replay onto other files refuses human changes unless allowed.
+func TestGitGuard_Replay(t *testing.T) {
+	dir := setupRepoSession(t)
+	fake := wholeFileLLM()
+
+	assert.NilError(t, cmd.ExecuteCodeCommand(context.Background(), fake, editRequest(dir, "main.go")))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package main\n\n// edited\n"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0600))
+
+	currentSession, err := session.LoadCurrentSession(dir)
+	assert.NilError(t, err)
+
+	step := currentSession.Steps[0]
+	files := editRequest(dir, "other.go", "new.go").AbsFilePaths
+
+	req, err := cmd.ReplayCodeRequest(dir, step, "", files)
+	assert.NilError(t, err)
+
+	req.EditFormat, req.PerFile = cmd.EditFormatWhole, true
+	restore := &cmd.RollbackRequest{StepID: step.ID}
+
+	err = cmd.ReplayStep(context.Background(), fake, currentSession, step, req, restore)
+	assert.Assert(t, errors.Is(err, cmd.ErrDirtyFiles), "Expected ErrDirtyFiles, got: %v", err)
+	assert.Assert(t, errors.Is(err, cmd.ErrUntrackedFiles), "Expected ErrUntrackedFiles, got: %v", err)
+	assert.Assert(t, cmp.Len(fake.prompts, 1), "Only the original step should have called the llm.")
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "main.go")), "package main\n\nfunc f1() {}\n"))
+
+	req.AllowDirty, req.AllowUntracked = true, true
+	assert.NilError(t, cmd.ReplayStep(context.Background(), fake, currentSession, step, req, restore))
+	assert.Assert(t, cmp.Len(step.History, 1))
+}
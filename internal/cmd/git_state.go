@@ -0,0 +1,40 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/chrisrob11/codeassistant/internal/git"
+	"github.com/chrisrob11/codeassistant/internal/session"
+)
+
+// captureGitState records the HEAD commit and dirty files of the project, leaving out the
+// session's own files. Projects that are not under git get an empty state.
+func captureGitState(currentDir string) (session.GitState, error) {
+	if !git.IsRepository(currentDir) {
+		return session.GitState{}, nil
+	}
+
+	commit, err := git.HeadCommit(currentDir)
+	if err != nil {
+		return session.GitState{}, err
+	}
+
+	files, err := git.UncommittedFiles(currentDir)
+	if err != nil {
+		return session.GitState{}, err
+	}
+
+	uncommittedFiles := make([]string, 0, len(files))
+
+	for _, file := range files {
+		if !session.IsSessionPath(filepath.ToSlash(file)) {
+			uncommittedFiles = append(uncommittedFiles, file)
+		}
+	}
+
+	return session.GitState{Commit: commit, UncommittedFiles: uncommittedFiles}, nil
+}
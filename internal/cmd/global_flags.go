@@ -40,10 +40,14 @@ func GlobalFlags() []cli.Flag {
 		},
 		&cli.IntFlag{
 			Name:    "llm-max-tokens",
-			Value:   200,
-			Usage:   "Maximum tokens for completion",
+			Usage:   "Maximum tokens for completion, sized from the input files when not set",
 			EnvVars: []string{"CA_LLM_MAX_TOKENS"},
 		},
+		&cli.IntFlag{
+			Name:    "llm-context-tokens",
+			Usage:   "Context window of the model, looked up from the model name when not set",
+			EnvVars: []string{"CA_LLM_CONTEXT_TOKENS"},
+		},
 		&cli.IntFlag{
 			Name:    "llm-max-retries",
 			Value:   3,
@@ -78,13 +82,14 @@ func GlobalFlags() []cli.Flag {
 // NewLLMConfigFromContext extracts the LLM configuration from the CLI context.
 func NewLLMConfigFromContext(c *cli.Context) *LLMConfig {
 	return &LLMConfig{
-		Provider:   c.String("llm-provider"),
-		Model:      c.String("llm-model"),
-		APIKey:     c.String("llm-api-key"),
-		Endpoint:   c.String("llm-endpoint"),
-		MaxTokens:  c.Int("llm-max-tokens"),
-		MaxRetries: c.Int("llm-max-retries"),
-		RetryDelay: c.Duration("llm-retry-delay"),
-		LogLevel:   gollm.LogLevel(c.Int("llm-log-level")),
+		Provider:      c.String("llm-provider"),
+		Model:         c.String("llm-model"),
+		APIKey:        c.String("llm-api-key"),
+		Endpoint:      c.String("llm-endpoint"),
+		MaxTokens:     c.Int("llm-max-tokens"),
+		ContextTokens: c.Int("llm-context-tokens"),
+		MaxRetries:    c.Int("llm-max-retries"),
+		RetryDelay:    c.Duration("llm-retry-delay"),
+		LogLevel:      gollm.LogLevel(c.Int("llm-log-level")),
 	}
 }
@@ -0,0 +1,357 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/git"
+	"github.com/chrisrob11/codeassistant/internal/session"
+)
+
+// Isolation errors.
+var (
+	ErrDetachedHead            = errors.New("HEAD is detached, check out the branch the session should start from")
+	ErrNoBaseCommit            = errors.New("repository has no commits to start the session branch from")
+	ErrUnknownFinish           = errors.New("unknown session branch finish")
+	ErrNoSessionBranch         = errors.New("session has no branch to finish")
+	ErrUncommittedChanges      = errors.New("session branch has uncommitted changes, commit them with ca commit first")
+	ErrDirtyWorkTree           = errors.New("work tree has uncommitted changes, commit or stash them first")
+	ErrBaseBranchNotCheckedOut = errors.New("base branch of the session is not checked out")
+	ErrIsolationFailed         = errors.New("failed to isolate session")
+)
+
+// What end-session does with the session branch.
+const (
+	FinishKeep    = "keep"
+	FinishSquash  = "squash"
+	FinishRebase  = "rebase"
+	FinishDiscard = "discard"
+)
+
+// Prefix of the branches created for sessions.
+const sessionBranchPrefix = "ca/"
+
+var branchUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// isolationRequest holds the options for giving a session its own branch.
+type isolationRequest struct {
+	Name     string // Session name, used for the default branch name
+	Branch   string // Branch to create, derived from the name when empty
+	Worktree string // Directory of a separate work tree, empty to switch branches in place
+}
+
+// sessionBranchName derives a branch name such as ca/fix-login from a session name.
+func sessionBranchName(name string) string {
+	slug := strings.Trim(branchUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if slug == "" {
+		slug = "session"
+	}
+
+	return sessionBranchPrefix + slug
+}
+
+// isolateSession creates the session branch at HEAD, either checked out in place or
+// in a new work tree, and returns where the session lives.
+func isolateSession(currentDir string, req *isolationRequest) (*session.Isolation, error) {
+	if !git.IsRepository(currentDir) {
+		return nil, ErrNotGitRepository
+	}
+
+	baseBranch, err := git.CurrentBranch(currentDir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	} else if baseBranch == "" {
+		return nil, ErrDetachedHead
+	}
+
+	baseCommit, err := git.HeadCommit(currentDir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	} else if baseCommit == "" {
+		return nil, ErrNoBaseCommit
+	}
+
+	isolation := &session.Isolation{Branch: req.Branch, BaseBranch: baseBranch, BaseCommit: baseCommit}
+	if isolation.Branch == "" {
+		isolation.Branch = sessionBranchName(req.Name)
+	}
+
+	if req.Worktree == "" {
+		if err := createBranchInPlace(currentDir, isolation.Branch); err != nil {
+			return nil, err
+		}
+
+		return isolation, nil
+	}
+
+	worktree, err := filepath.Abs(req.Worktree)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+	}
+
+	if err := git.AddWorktree(currentDir, worktree, isolation.Branch); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	isolation.Worktree, isolation.RepoDir = worktree, currentDir
+
+	return isolation, nil
+}
+
+// createBranchInPlace checks out a new branch at HEAD in the current work tree. Discarding
+// the session switches back with its changes thrown away, so uncommitted changes made
+// before the session would be lost with them and are refused.
+func createBranchInPlace(currentDir, branch string) error {
+	changes, err := git.TrackedChanges(currentDir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	if len(changes) > 0 {
+		return fmt.Errorf("%w: %s", ErrDirtyWorkTree, strings.Join(changes, ", "))
+	}
+
+	if err := git.CreateBranch(currentDir, branch); err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	return nil
+}
+
+// undoIsolation removes the branch and work tree of a session that failed to start.
+func undoIsolation(currentDir string, isolation *session.Isolation) error {
+	if isolation.Worktree != "" {
+		if err := git.RemoveWorktree(currentDir, isolation.Worktree); err != nil {
+			return err
+		}
+	} else if err := git.Switch(currentDir, isolation.BaseBranch, false); err != nil {
+		return err
+	}
+
+	return git.DeleteBranch(currentDir, isolation.Branch)
+}
+
+// executeEndSession ends the session, first merging or discarding its branch as requested.
+func executeEndSession(currentDir, finish string) error {
+	currentSession, err := session.LoadCurrentSession(currentDir)
+	if err != nil {
+		// Reports a missing session the same way as without a branch
+		return session.EndSession(currentDir)
+	}
+
+	isolation := currentSession.Isolation
+
+	switch {
+	case isolation == nil && finish != FinishKeep:
+		return ErrNoSessionBranch
+	case finish != FinishKeep:
+		return finishIsolation(currentDir, currentSession, finish)
+	}
+
+	if err := session.EndSession(currentDir); err != nil {
+		return err
+	}
+
+	if isolation != nil {
+		fmt.Printf("Session branch %s kept, end with --finish squash, rebase or discard to merge or drop it\n",
+			isolation.Branch)
+	}
+
+	return nil
+}
+
+// finishIsolation merges or discards the session branch, archives the session next to
+// the base branch and removes the branch and any work tree of the session.
+func finishIsolation(currentDir string, currentSession *session.Session, finish string) error {
+	isolation := currentSession.Isolation
+
+	// The base branch lives in the main work tree when the session has its own
+	repoDir := currentDir
+	if isolation.Worktree != "" {
+		repoDir = isolation.RepoDir
+	}
+
+	if err := finishSessionBranch(currentDir, repoDir, currentSession, finish); err != nil {
+		return err
+	}
+
+	if err := session.EndSessionInto(currentDir, repoDir); err != nil {
+		return err
+	}
+
+	if isolation.Worktree != "" {
+		if err := git.RemoveWorktree(repoDir, isolation.Worktree); err != nil {
+			return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+		}
+	}
+
+	if err := git.DeleteBranch(repoDir, isolation.Branch); err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	fmt.Printf("✅ Session branch %s finished with %s onto %s\n", isolation.Branch, finish, isolation.BaseBranch)
+
+	return nil
+}
+
+// finishSessionBranch brings the changes of the session branch onto the base branch,
+// or throws them away, leaving the base branch checked out in repoDir.
+func finishSessionBranch(currentDir, repoDir string, currentSession *session.Session, finish string) error {
+	isolation := currentSession.Isolation
+
+	switch finish {
+	case FinishSquash:
+		return squashSessionBranch(currentDir, repoDir, currentSession.Name, isolation)
+	case FinishRebase:
+		return rebaseSessionBranch(currentDir, repoDir, isolation)
+	case FinishDiscard:
+		// A separate work tree is removed with its changes afterwards
+		if isolation.Worktree != "" {
+			return nil
+		}
+
+		if err := removeCreatedFiles(currentDir, currentSession); err != nil {
+			return err
+		}
+
+		if err := git.Switch(currentDir, isolation.BaseBranch, true); err != nil {
+			return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFinish, finish)
+	}
+}
+
+// removeCreatedFiles deletes the files that didn't exist before the session. Switching
+// branches discards changes to tracked files only, and created files stay untracked
+// until they are committed.
+func removeCreatedFiles(currentDir string, currentSession *session.Session) error {
+	firstPreHash := map[string]string{}
+	paths := []string{}
+
+	for _, step := range currentSession.Steps {
+		for _, snapshot := range step.CombinedSnapshots() {
+			if _, ok := firstPreHash[snapshot.Path]; !ok {
+				firstPreHash[snapshot.Path] = snapshot.PreHash
+				paths = append(paths, snapshot.Path)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		if firstPreHash[path] != "" {
+			continue
+		}
+
+		absPath, err := isValidFilePath(currentDir, filepath.Join(currentDir, path))
+		if err != nil {
+			return err
+		}
+
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+		}
+	}
+
+	return nil
+}
+
+// squashSessionBranch commits the changes of the session branch onto the base branch
+// as a single commit named after the session.
+func squashSessionBranch(currentDir, repoDir, name string, isolation *session.Isolation) error {
+	if err := checkBaseReady(currentDir, repoDir, isolation); err != nil {
+		return err
+	}
+
+	if err := git.MergeSquash(repoDir, isolation.Branch); err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	staged, err := git.StagedDiff(repoDir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	// Nothing to commit when the session made no changes
+	if staged == "" {
+		return nil
+	}
+
+	if _, err := git.Commit(repoDir, name); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToCommit, err)
+	}
+
+	return nil
+}
+
+// rebaseSessionBranch replays the session commits onto the base branch and fast-forwards it.
+func rebaseSessionBranch(currentDir, repoDir string, isolation *session.Isolation) error {
+	if err := checkCommitted(currentDir); err != nil {
+		return err
+	}
+
+	if err := git.Rebase(currentDir, isolation.BaseBranch); err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	if err := checkBaseReady(currentDir, repoDir, isolation); err != nil {
+		return err
+	}
+
+	if err := git.MergeFastForward(repoDir, isolation.Branch); err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	return nil
+}
+
+// checkBaseReady makes sure the session changes are committed and the base branch is
+// checked out in repoDir, switching to it when the session shares the work tree.
+func checkBaseReady(currentDir, repoDir string, isolation *session.Isolation) error {
+	if err := checkCommitted(currentDir); err != nil {
+		return err
+	}
+
+	if repoDir == currentDir {
+		if err := git.Switch(repoDir, isolation.BaseBranch, false); err != nil {
+			return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+		}
+
+		return nil
+	}
+
+	branch, err := git.CurrentBranch(repoDir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	if branch != isolation.BaseBranch {
+		return fmt.Errorf("%w: %s is on %q instead of %q", ErrBaseBranchNotCheckedOut, repoDir, branch, isolation.BaseBranch)
+	}
+
+	return nil
+}
+
+// checkCommitted refuses to finish a branch while tracked files have uncommitted changes.
+func checkCommitted(dir string) error {
+	changes, err := git.TrackedChanges(dir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+
+	if len(changes) > 0 {
+		return fmt.Errorf("%w: %s", ErrUncommittedChanges, strings.Join(changes, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// commitOnBranch changes main.go in dir and commits it.
+func commitOnBranch(t *testing.T, dir, content, message string) {
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(content), 0600))
+	gitOutput(t, dir, "commit", "-q", "-am", message)
+}
+
+// TestSession_BranchSquash verifies a session branch is created at start and squashed
+// back onto the base branch at the end.
+func TestSession_BranchSquash(t *testing.T) {
+	dir := initRepo(t)
+	base := gitOutput(t, dir, "symbolic-ref", "--short", "HEAD")
+	baseCommit := gitOutput(t, dir, "rev-parse", "HEAD")
+
+	req := &session.StartSessionRequest{Dir: dir, Name: "Fix Login"}
+	assert.NilError(t, cmd.StartIsolatedSession(dir, req, &cmd.IsolationRequest{Name: req.Name}))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "symbolic-ref", "--short", "HEAD"), "ca/fix-login"))
+
+	currentSession, err := session.LoadCurrentSession(dir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, currentSession.Isolation, &session.Isolation{
+		Branch: "ca/fix-login", BaseBranch: base, BaseCommit: baseCommit,
+	})
+
+	commitOnBranch(t, dir, "package main\n\nfunc a() {}\n", "first")
+	commitOnBranch(t, dir, "package main\n\nfunc b() {}\n", "second")
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package other\n"), 0600))
+
+	err = cmd.ExecuteEndSession(dir, cmd.FinishSquash)
+	assert.Assert(t, errors.Is(err, cmd.ErrUncommittedChanges), "Expected ErrUncommittedChanges, got: %v", err)
+
+	gitOutput(t, dir, "checkout", "--", "other.go")
+	assert.NilError(t, cmd.ExecuteEndSession(dir, cmd.FinishSquash))
+
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "symbolic-ref", "--short", "HEAD"), base))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "log", "-1", "--format=%s"), "Fix Login"))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "rev-parse", "HEAD~1"), baseCommit))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "main.go")), "package main\n\nfunc b() {}\n"))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "branch", "--list", "ca/*"), ""))
+
+	_, err = os.Stat(session.BuildCurrentSessionFilePath(dir))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+// TestSession_BranchRebase verifies the session commits are replayed onto the base branch.
+func TestSession_BranchRebase(t *testing.T) {
+	dir := initRepo(t)
+	base := gitOutput(t, dir, "symbolic-ref", "--short", "HEAD")
+
+	req := &session.StartSessionRequest{Dir: dir, Name: "rebase"}
+	assert.NilError(t, cmd.StartIsolatedSession(dir, req, &cmd.IsolationRequest{Name: req.Name, Branch: "work"}))
+
+	commitOnBranch(t, dir, "package main\n\nfunc a() {}\n", "feat: add a")
+	assert.NilError(t, cmd.ExecuteEndSession(dir, cmd.FinishRebase))
+
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "symbolic-ref", "--short", "HEAD"), base))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "log", "-1", "--format=%s"), "feat: add a"))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "branch", "--list", "work"), ""))
+}
+
+// TestSession_WorktreeDiscard verifies a worktree session lives in its own directory and
+// is archived in the main work tree when it is discarded.
+func TestSession_WorktreeDiscard(t *testing.T) {
+	dir := initRepo(t)
+	baseCommit := gitOutput(t, dir, "rev-parse", "HEAD")
+	worktree := filepath.Join(t.TempDir(), "session")
+
+	req := &session.StartSessionRequest{Dir: dir, Name: "experiment"}
+	assert.NilError(t, cmd.StartIsolatedSession(dir, req, &cmd.IsolationRequest{Name: req.Name, Worktree: worktree}))
+
+	_, err := os.Stat(session.BuildCurrentSessionFilePath(worktree))
+	assert.NilError(t, err)
+
+	commitOnBranch(t, worktree, "package main\n\nfunc a() {}\n", "experiment")
+
+	err = cmd.ExecuteCodeCommand(context.Background(), commitLLM(""), &cmd.CodeRequest{
+		CurrentDir: worktree, Prompt: "add main", AbsFilePaths: []string{filepath.Join(worktree, "main.go")},
+		EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, cmd.ExecuteEndSession(worktree, cmd.FinishDiscard))
+
+	_, err = os.Stat(worktree)
+	assert.Assert(t, os.IsNotExist(err))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "rev-parse", "HEAD"), baseCommit))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "branch", "--list", "ca/*"), ""))
+
+	archives, err := filepath.Glob(filepath.Join(session.BuildSessionHistoryPath(dir), "*.json"))
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(archives, 1))
+
+	// The snapshots of the archived session are still readable from the main work tree
+	data, err := os.ReadFile(archives[0])
+	assert.NilError(t, err)
+
+	var archived session.Session
+	assert.NilError(t, json.Unmarshal(data, &archived))
+	assert.Assert(t, cmp.Len(archived.Steps, 1))
+
+	blobStore := session.NewBlobStore(dir)
+	for _, snapshot := range archived.Steps[0].FilesDiff.Snapshots {
+		_, err := blobStore.Get(snapshot.PreHash)
+		assert.NilError(t, err)
+		_, err = blobStore.Get(snapshot.PostHash)
+		assert.NilError(t, err)
+	}
+}
+
+// TestSession_InPlaceDiscard verifies discarding a session on a branch in place drops its
+// edits and the files it created, which git doesn't track yet.
+func TestSession_InPlaceDiscard(t *testing.T) {
+	dir := initRepo(t)
+	base := gitOutput(t, dir, "symbolic-ref", "--short", "HEAD")
+
+	req := &session.StartSessionRequest{Dir: dir, Name: "discard"}
+	assert.NilError(t, cmd.StartIsolatedSession(dir, req, &cmd.IsolationRequest{Name: req.Name}))
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "FILE: main.go\n<<<<<<< SEARCH\npackage main\n=======\npackage main\n\nfunc main() {}\n>>>>>>> REPLACE\n" +
+			"FILE: new.go\n<<<<<<< SEARCH\n=======\npackage main\n>>>>>>> REPLACE\n", nil
+	}}
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "split main", AbsFilePaths: []string{filepath.Join(dir, "main.go")},
+		EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, cmd.ExecuteEndSession(dir, cmd.FinishDiscard))
+
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "symbolic-ref", "--short", "HEAD"), base))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "status", "--porcelain", "--", "*.go"), ""))
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "branch", "--list", "ca/*"), ""))
+
+	_, err = os.Stat(filepath.Join(dir, "new.go"))
+	assert.Assert(t, os.IsNotExist(err), "Created files should be removed, got: %v", err)
+}
+
+// TestSession_InPlaceRefusesDirtyTree verifies a session branch isn't started in place over
+// uncommitted changes, which discarding the session would throw away.
+func TestSession_InPlaceRefusesDirtyTree(t *testing.T) {
+	dir := initRepo(t)
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package main // mine\n"), 0600))
+
+	req := &session.StartSessionRequest{Dir: dir, Name: "dirty"}
+	err := cmd.StartIsolatedSession(dir, req, &cmd.IsolationRequest{Name: req.Name})
+	assert.Assert(t, errors.Is(err, cmd.ErrDirtyWorkTree), "Expected ErrDirtyWorkTree, got: %v", err)
+	assert.Assert(t, cmp.Equal(gitOutput(t, dir, "branch", "--list", "ca/*"), ""))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "other.go")), "package main // mine\n"))
+
+	_, err = os.Stat(session.BuildCurrentSessionFilePath(dir))
+	assert.Assert(t, os.IsNotExist(err), "No session should be started, got: %v", err)
+}
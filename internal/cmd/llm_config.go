@@ -6,6 +6,7 @@ import (
 	"slices"
 	"time"
 
+	"github.com/chrisrob11/codeassistant/internal/tokens"
 	"github.com/teilomillet/gollm"
 	"github.com/teilomillet/gollm/config"
 )
@@ -16,6 +17,9 @@ var (
 	ErrAPITokenRequired = errors.New("api token is required")
 )
 
+// Completion limit used when neither the user nor the command sized it.
+const defaultMaxTokens = 4096
+
 // A list of providers that require an API token.
 var providersRequireAPIToken = []string{
 	"openai",
@@ -40,6 +44,8 @@ type LLMConfig struct {
 	RetryDelay time.Duration  // Common
 	LogLevel   gollm.LogLevel // Common
 
+	ContextTokens int // Prompt and response limit of the model, not sent to the provider
+
 	defaultsSet bool // internal flag to ensure we only set defaults once
 }
 
@@ -54,7 +60,11 @@ func (c *LLMConfig) setDefaults() {
 	c.defaultsSet = true
 
 	if c.MaxTokens == 0 {
-		c.MaxTokens = 200
+		c.MaxTokens = defaultMaxTokens
+	}
+
+	if c.ContextTokens == 0 {
+		c.ContextTokens = tokens.ContextWindow(c.Model)
 	}
 
 	if c.MaxRetries == 0 {
@@ -0,0 +1,110 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/session"
+)
+
+// findCurrentDir returns the session root found from the working directory, along with
+// the working directory itself, which relative paths on the command line start from.
+func findCurrentDir() (currentDir, workDir string, err error) {
+	workDir, err = os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrFailedToGetCurrentDir, err)
+	}
+
+	currentDir, err = session.FindSessionRoot(workDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	return currentDir, workDir, nil
+}
+
+// fromWorkDir makes a path given on the command line absolute from the working directory,
+// leaving empty paths empty.
+func fromWorkDir(workDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(workDir, path)
+}
+
+// isValidFilePath ensures the file is inside the current directory and returns its
+// absolute path. The path must be inside both as written and with symlinks resolved, so
+// neither ../ nor a link can lead a read or write out of the project.
+func isValidFilePath(currentDir, filePath string) (string, error) {
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+	}
+
+	if !isWithinDir(filepath.Clean(currentDir), absFilePath) {
+		return "", fmt.Errorf("%w: %s", ErrFileOutsideCurrentDir, filePath)
+	}
+
+	resolvedDir, err := resolvePath(currentDir)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedFile, err := resolvePath(absFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	if !isWithinDir(resolvedDir, resolvedFile) {
+		return "", fmt.Errorf("%w: %s links to %s", ErrFileOutsideCurrentDir, filePath, resolvedFile)
+	}
+
+	return absFilePath, nil
+}
+
+// isWithinDir reports whether the clean absolute path is dir or below it, comparing
+// whole path segments so /repo-other is not inside /repo.
+func isWithinDir(dir, path string) bool {
+	relPath, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// resolvePath resolves the symlinks of an absolute path. The file and its parent
+// directories may not exist yet, in which case the missing part is kept as written
+// below the deepest directory that does. A symlink to a missing file is refused, as
+// writing to it would create its target wherever it points.
+func resolvePath(path string) (string, error) {
+	missing := ""
+
+	for current := path; ; current = filepath.Dir(current) {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+		}
+
+		if _, err := os.Lstat(current); err == nil {
+			return "", fmt.Errorf("%w: %s", ErrDanglingSymlink, current)
+		}
+
+		if filepath.Dir(current) == current {
+			return path, nil
+		}
+
+		missing = filepath.Join(filepath.Base(current), missing)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"gotest.tools/v3/assert"
+)
+
+// setupLinkedProject creates a project next to a sibling directory whose name starts with
+// the project's, with symlinks pointing inside and outside the project.
+func setupLinkedProject(t *testing.T) (base, project string) {
+	t.Helper()
+
+	base = t.TempDir()
+	project = filepath.Join(base, "repo")
+	other := filepath.Join(base, "repo-other")
+
+	assert.NilError(t, os.MkdirAll(filepath.Join(project, "sub"), 0750))
+	assert.NilError(t, os.MkdirAll(other, 0750))
+	assert.NilError(t, os.WriteFile(filepath.Join(project, "main.go"), []byte("package main\n"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(other, "secret.go"), []byte("package secret\n"), 0600))
+
+	links := map[string]string{
+		"out":      other,
+		"secret":   filepath.Join(other, "secret.go"),
+		"inner":    filepath.Join(project, "sub"),
+		"relative": "sub",
+		"dangling": filepath.Join(base, "missing.go"),
+	}
+
+	for name, target := range links {
+		assert.NilError(t, os.Symlink(target, filepath.Join(project, name)))
+	}
+
+	assert.NilError(t, os.Symlink(project, filepath.Join(base, "link-to-repo")))
+
+	return base, project
+}
+
+// TestIsValidFilePath verifies paths are contained in the project by whole segments and
+// with symlinks resolved.
+func TestIsValidFilePath(t *testing.T) {
+	base, project := setupLinkedProject(t)
+
+	tests := []struct {
+		name    string
+		dir     string
+		path    string
+		wantErr error
+	}{
+		{"file", project, "main.go", nil},
+		{"new file in new directory", project, "sub/new/file.go", nil},
+		{"link inside project", project, "inner/file.go", nil},
+		{"relative link inside project", project, "relative/file.go", nil},
+		{"dot segments staying inside", project, "sub/../main.go", nil},
+		{"project through linked root", filepath.Join(base, "link-to-repo"), "main.go", nil},
+		{"parent directory", project, "../repo-other/secret.go", cmd.ErrFileOutsideCurrentDir},
+		{"sibling sharing prefix", project, filepath.Join(base, "repo-other", "secret.go"), cmd.ErrFileOutsideCurrentDir},
+		{"linked directory outside", project, "out/secret.go", cmd.ErrFileOutsideCurrentDir},
+		{"new file in linked directory outside", project, "out/new.go", cmd.ErrFileOutsideCurrentDir},
+		{"linked file outside", project, "secret", cmd.ErrFileOutsideCurrentDir},
+		{"dangling link", project, "dangling", cmd.ErrDanglingSymlink},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(tt.dir, path)
+			}
+
+			absPath, err := cmd.IsValidFilePath(tt.dir, path)
+			if tt.wantErr != nil {
+				assert.Assert(t, errors.Is(err, tt.wantErr), "Expected %v, got: %v", tt.wantErr, err)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, absPath, filepath.Clean(path))
+		})
+	}
+}
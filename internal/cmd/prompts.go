@@ -0,0 +1,297 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/chunk"
+	"github.com/chrisrob11/codeassistant/internal/edit"
+)
+
+// Edit formats the llm can be asked to respond with.
+const (
+	EditFormatSearchReplace = "search-replace"
+	EditFormatWhole         = "whole"
+)
+
+// Prompt errors.
+var (
+	ErrUnknownEditFormat = errors.New("unknown edit format")
+)
+
+// Instructions describing the search/replace edit format to the llm.
+const searchReplaceInstructions = `Respond ONLY with search/replace blocks in this exact format:
+
+<<<<<<< SEARCH
+lines copied exactly from the current file
+=======
+the lines that replace them
+>>>>>>> REPLACE
+
+Rules:
+- The SEARCH section must match the current file exactly, including indentation and comments.
+- Each SEARCH section must match exactly one place in the file; include enough lines to be unique.
+- Use several small blocks rather than one large block, and keep them in file order.
+- To add code, search for the lines next to where it goes and repeat them in the replacement.
+- If the file is empty, use an empty SEARCH section.
+- Do not output the whole file and do not add any explanation.`
+
+// Instructions asking the llm to return the whole file.
+const wholeFileInstructions = `Respond ONLY with the complete updated contents of the file.
+Do not wrap the file in markdown code fences and do not add any explanation.`
+
+// validateEditFormat checks that the edit format is one we can apply.
+func validateEditFormat(format string) error {
+	switch format {
+	case EditFormatSearchReplace, EditFormatWhole:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownEditFormat, format)
+	}
+}
+
+// buildEditPrompt builds the prompt asking the llm to change a single file.
+func buildEditPrompt(prompt, path, content, format string, refs *promptReferences) string {
+	instructions := searchReplaceInstructions
+	if format == EditFormatWhole {
+		instructions = wholeFileInstructions
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "You are editing the file %s.\n\n", path)
+	fmt.Fprintf(&b, "Requested change: %s\n\n", prompt)
+	fmt.Fprintf(&b, "%s\n\n", instructions)
+	writeReferences(&b, refs)
+	fmt.Fprintf(&b, "Current contents of %s:\n%s", path, content)
+
+	return b.String()
+}
+
+// buildFixPrompt builds the change request sent when a check fails after a step.
+func buildFixPrompt(prompt, checkOutput string) string {
+	return fmt.Sprintf("The earlier change %q was applied but the checks now fail with this output:\n\n%s\n"+
+		"Fix the file so the checks pass while keeping the requested change.", prompt, checkOutput)
+}
+
+// Instructions for naming the file each search/replace block applies to.
+const batchSearchReplaceInstructions = `Before the blocks for each file, write a line with the file path:
+
+FILE: path/to/file.go
+
+Only include files that need changes.
+To create a new file, write its FILE: line followed by a single block with an empty SEARCH section.
+To delete a file, write a line of the form: DELETE: path/to/file.go`
+
+// Instructions asking the llm to return whole files in delimited sections.
+const batchWholeFileInstructions = `Respond ONLY with the complete updated contents of each file that changes,
+each one wrapped in the same delimiters used below:
+
+` + "=== FILE: path/to/file.go ===\n...\n=== END FILE ===" + `
+
+Leave out files that do not change. To create a new file, add a section with its path.
+To delete a file, write a line of the form: === DELETE: path/to/file.go ===
+Do not use markdown code fences and do not add any explanation.`
+
+// promptFile is a file included in a prompt.
+type promptFile struct {
+	Path    string
+	Content string
+}
+
+// promptReferences is read-only material sent alongside the files being edited.
+type promptReferences struct {
+	ContextFiles []promptFile
+	RepoMap      string // Summary of Go symbols defined elsewhere in the project
+}
+
+// buildBatchPrompt builds a single prompt asking the llm to change several files together.
+func buildBatchPrompt(prompt string, files []promptFile, refs *promptReferences, format string) string {
+	instructions := searchReplaceInstructions + "\n\n" + batchSearchReplaceInstructions
+	if format == EditFormatWhole {
+		instructions = batchWholeFileInstructions
+	}
+
+	var b strings.Builder
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+
+	fmt.Fprintf(&b, "You are editing these files together: %s.\n", strings.Join(paths, ", "))
+	fmt.Fprintln(&b, "Keep the changes consistent across files, e.g. update every use of a renamed identifier.")
+	fmt.Fprintf(&b, "\nRequested change: %s\n\n", prompt)
+	fmt.Fprintf(&b, "%s\n\n", instructions)
+	writeReferences(&b, refs)
+	fmt.Fprintln(&b, "Current contents of the files:")
+
+	for _, file := range files {
+		writePromptFile(&b, file)
+	}
+
+	return b.String()
+}
+
+// writePromptFile writes a file between the multi-file delimiters.
+func writePromptFile(b *strings.Builder, file promptFile) {
+	fmt.Fprintf(b, edit.FileStartFormat+"\n", file.Path)
+	b.WriteString(file.Content)
+
+	if !strings.HasSuffix(file.Content, "\n") {
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintln(b, edit.FileEnd)
+}
+
+// writeReferences writes the read-only reference material, if any, ahead of the files being edited.
+func writeReferences(b *strings.Builder, refs *promptReferences) {
+	if refs.RepoMap != "" {
+		fmt.Fprintf(b, "Symbols defined elsewhere in the project, for reference:\n%s\n", refs.RepoMap)
+	}
+
+	if len(refs.ContextFiles) == 0 {
+		return
+	}
+
+	fmt.Fprintln(b, "Reference files for context only. Do NOT change, create or delete them:")
+
+	for _, file := range refs.ContextFiles {
+		writePromptFile(b, file)
+	}
+
+	fmt.Fprintln(b)
+}
+
+// buildChunkSelectPrompt asks the llm which sections of a large file the change touches.
+func buildChunkSelectPrompt(prompt, path string, chunks []chunk.Chunk) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "You are planning a change to the file %s, which is too large to edit at once.\n\n", path)
+	fmt.Fprintf(&b, "Requested change: %s\n\n", prompt)
+	fmt.Fprintln(&b, "The file is split into these sections:")
+	writeOutline(&b, chunks)
+	fmt.Fprintln(&b, "\nReply ONLY with the numbers of the sections that must change, separated by commas,")
+	fmt.Fprintln(&b, `or "none" if nothing needs to change. Include section 1 if imports must be added.`)
+
+	return b.String()
+}
+
+// buildChunkEditPrompt builds the prompt asking the llm to change one section of a large file.
+// Sections are always edited with search/replace blocks since they aren't complete files.
+func buildChunkEditPrompt(prompt, path string, chunks []chunk.Chunk, index int, refs *promptReferences) string {
+	section := chunks[index]
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "You are editing section %d (lines %d-%d) of the file %s, which is too large to show at once.\n",
+		index+1, section.StartLine, section.EndLine, path)
+	fmt.Fprintln(&b, "Only change this section; the other sections are edited separately.")
+	fmt.Fprintln(&b, "\nSections of the file:")
+	writeOutline(&b, chunks)
+	fmt.Fprintf(&b, "\nRequested change: %s\n\n", prompt)
+	fmt.Fprintf(&b, "%s\n\n", searchReplaceInstructions)
+	writeReferences(&b, refs)
+	fmt.Fprintf(&b, "Current contents of section %d:\n%s", index+1, section.Content)
+
+	return b.String()
+}
+
+// writeOutline writes the numbered list of sections of a file.
+func writeOutline(b *strings.Builder, chunks []chunk.Chunk) {
+	for i, section := range chunks {
+		fmt.Fprintf(b, "%d. lines %d-%d: %s\n", i+1, section.StartLine, section.EndLine, section.Name)
+	}
+}
+
+// Instructions asking the llm to return a whole declaration.
+const wholeDeclarationInstructions = `Respond ONLY with the complete updated declaration, including its doc comment.
+Do not include the rest of the file, do not wrap it in markdown code fences and do not add any explanation.`
+
+// buildSymbolPrompt builds the prompt asking the llm to change a single declaration of a file.
+func buildSymbolPrompt(
+	prompt, path, symbol, format string, decls []chunk.Chunk, decl string, refs *promptReferences,
+) string {
+	instructions := searchReplaceInstructions
+	if format == EditFormatWhole {
+		instructions = wholeDeclarationInstructions
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "You are editing the declaration of %s in the file %s.\n", symbol, path)
+	fmt.Fprintln(&b, "Only this declaration is shown and only it can change; the rest of the file is kept as it is.")
+	fmt.Fprintf(&b, "\nRequested change: %s\n\n", prompt)
+	fmt.Fprintf(&b, "%s\n\n", instructions)
+	writeReferences(&b, refs)
+
+	if len(decls) > 0 {
+		fmt.Fprintf(&b, "Package clause and imports of %s:\n%s\n", path, decls[0].Content)
+		fmt.Fprintf(&b, "Declarations in %s:\n", path)
+		writeOutline(&b, decls)
+		fmt.Fprintln(&b)
+	}
+
+	fmt.Fprintf(&b, "Current declaration of %s:\n%s", symbol, decl)
+
+	return b.String()
+}
+
+// Instructions for the analysis returned in summary mode.
+const summaryInstructions = `Respond with your analysis as plain text or markdown.
+Refer to code by file path and line or identifier. Do not rewrite the files and do not output edit blocks.`
+
+// buildSummaryPrompt builds the prompt asking the llm to analyze files without changing them.
+// When symbols are given the analysis focuses on those declarations.
+func buildSummaryPrompt(prompt string, files []promptFile, symbols []string, refs *promptReferences) string {
+	var b strings.Builder
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+
+	fmt.Fprintf(&b, "You are reviewing these files: %s.\n", strings.Join(paths, ", "))
+
+	if len(symbols) > 0 {
+		fmt.Fprintf(&b, "Focus on these declarations: %s.\n", strings.Join(symbols, ", "))
+	}
+
+	fmt.Fprintf(&b, "\nRequest: %s\n\n", prompt)
+	fmt.Fprintf(&b, "%s\n\n", summaryInstructions)
+	writeReferences(&b, refs)
+	fmt.Fprintln(&b, "Current contents of the files:")
+
+	for _, file := range files {
+		writePromptFile(&b, file)
+	}
+
+	return b.String()
+}
+
+// buildCommitMessagePrompt asks the llm for a conventional commit message for the staged diff.
+func buildCommitMessagePrompt(prompts []string, diff string) string {
+	var b strings.Builder
+
+	fmt.Fprintln(&b, "Write a git commit message for the changes below, following the Conventional Commits format:")
+	fmt.Fprintln(&b, "a subject line such as \"fix(parser): handle empty input\" of at most 72 characters, then")
+	fmt.Fprintln(&b, "optionally a blank line and a short body explaining why. Respond ONLY with the message.")
+
+	if len(prompts) > 0 {
+		fmt.Fprintln(&b, "\nThe changes were made with these requests:")
+
+		for _, prompt := range prompts {
+			fmt.Fprintf(&b, "- %s\n", prompt)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nStaged diff:\n%s\n", diff)
+
+	return b.String()
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/fileselect"
+	"github.com/chrisrob11/codeassistant/internal/repomap"
+)
+
+// Token budget for the summary of Go symbols unless set with --repo-map-tokens.
+const defaultRepoMapTokens = 1024
+
+// loadReferences reads the context files and summarizes the project symbols most
+// relevant to the request.
+func loadReferences(req *codeRequest) (*promptReferences, error) {
+	contextFiles, err := readContextFiles(req)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := &promptReferences{ContextFiles: contextFiles}
+	if req.RepoMapTokens <= 0 {
+		return refs, nil
+	}
+
+	index, err := repomap.Build(req.CurrentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	focus := &repomap.Focus{}
+
+	var text strings.Builder
+
+	text.WriteString(req.Prompt)
+
+	for _, file := range append(slices.Clone(req.AbsFilePaths), req.ContextFiles...) {
+		relPath, err := filepath.Rel(req.CurrentDir, file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+		}
+
+		focus.Files = append(focus.Files, filepath.ToSlash(relPath))
+
+		if content, err := req.readFile(file); err == nil {
+			text.WriteString("\n")
+			text.Write(content)
+		}
+	}
+
+	focus.Text = text.String()
+
+	refs.RepoMap = index.Summary(focus, req.RepoMapTokens, req.Budget.count)
+
+	return refs, nil
+}
+
+// selectContextFiles expands the --context arguments into validated absolute paths,
+// leaving out files that are also being modified.
+func selectContextFiles(currentDir, workDir string, patterns, absFilePaths []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	selected, err := fileselect.Select(&fileselect.Options{Root: currentDir, BaseDir: workDir, Patterns: patterns})
+	if err != nil {
+		return nil, err
+	}
+
+	contextFiles := []string{}
+
+	for _, f := range selected {
+		absPath, err := isValidFilePath(currentDir, f)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(absFilePaths, absPath) {
+			contextFiles = append(contextFiles, absPath)
+		}
+	}
+
+	return contextFiles, nil
+}
+
+// readContextFiles reads the read-only context files for inclusion in prompts.
+func readContextFiles(req *codeRequest) ([]promptFile, error) {
+	contextFiles := make([]promptFile, 0, len(req.ContextFiles))
+
+	for _, file := range req.ContextFiles {
+		relPath, err := filepath.Rel(req.CurrentDir, file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+		}
+
+		// nolint:gosec //Why: files are validated within a specific path
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrFailedToReadFile, relPath, err)
+		}
+
+		contextFiles = append(contextFiles, promptFile{Path: filepath.ToSlash(relPath), Content: string(content)})
+	}
+
+	return contextFiles, nil
+}
+
+// checkNotContextFile refuses a response that changes one of the read-only context files.
+func checkNotContextFile(contextFiles []promptFile, relPath string) error {
+	for _, file := range contextFiles {
+		if file.Path == relPath {
+			return fmt.Errorf("%w: %s is a read-only context file", ErrUnexpectedFile, relPath)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,381 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/chrisrob11/codeassistant/internal/filetx"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"github.com/teilomillet/gollm"
+	cli "github.com/urfave/cli/v2"
+)
+
+// Replay errors.
+var (
+	ErrStepNotReplayable = errors.New("step cannot be replayed")
+	ErrNoStepToRevise    = errors.New("session has no step to revise")
+)
+
+// ReplayStepCommand re-runs a recorded step with an optionally changed prompt or files.
+func ReplayStepCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "replay-step",
+		Usage: "Re-run a previous AI step, replacing its result",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "step",
+				Usage: "Specify the step to replay",
+			},
+			&cli.StringFlag{
+				Name:  "prompt",
+				Usage: "Prompt to use instead of the recorded one",
+			},
+			&cli.StringSliceFlag{
+				Name:    "files",
+				Aliases: []string{"f"},
+				Usage:   "Files, directories or globs to use instead of the recorded ones",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "Files, directories or globs to leave out of --files",
+			},
+			&cli.BoolFlag{
+				Name:  "per-file",
+				Usage: "Apply the prompt to each file individually",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Preview the replayed changes without modifying files",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Restore files even if they were edited after the step",
+			},
+			&cli.BoolFlag{
+				Name:  "merge",
+				Usage: "Three-way merge files that were edited after the step",
+			},
+			&cli.BoolFlag{
+				Name:    "allow-dirty",
+				Usage:   "Modify files that have uncommitted changes not made by the session",
+				EnvVars: []string{"CA_ALLOW_DIRTY"},
+			},
+			&cli.BoolFlag{
+				Name:    "allow-untracked",
+				Usage:   "Modify files that are not tracked by git",
+				EnvVars: []string{"CA_ALLOW_UNTRACKED"},
+			},
+			&cli.StringFlag{
+				Name:    "edit-format",
+				Value:   EditFormatSearchReplace,
+				Usage:   "How the LLM returns changes (search-replace, whole)",
+				EnvVars: []string{"CA_EDIT_FORMAT"},
+			},
+		},
+		Action: func(c *cli.Context) error {
+			currentDir, workDir, err := findCurrentDir()
+			if err != nil {
+				return err
+			}
+
+			if c.Int("step") <= 0 {
+				return ErrStepMustBeSpecified
+			}
+
+			currentSession, err := session.LoadCurrentSession(currentDir)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrFailedToLoadSession, err)
+			}
+
+			step, err := currentSession.FindStep(c.Int("step"))
+			if err != nil {
+				return err
+			}
+
+			req, llm, err := newReplayRequest(c, currentDir, workDir, step)
+			if err != nil {
+				return err
+			}
+
+			// Ctrl-C cancels any LLM calls still in flight
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			return replayStep(ctx, llm, currentSession, step, req, &rollbackRequest{
+				StepID: step.ID,
+				Force:  c.Bool("force"),
+				Merge:  c.Bool("merge"),
+			})
+		},
+	}
+}
+
+// newReplayRequest builds the request re-running the step from its recorded command and
+// the command line overrides, along with the llm to send it to.
+func newReplayRequest(
+	c *cli.Context, currentDir, workDir string, step *session.Step,
+) (*codeRequest, gollm.LLM, error) {
+	editFormat := c.String("edit-format")
+	if err := validateEditFormat(editFormat); err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+
+	if len(c.StringSlice("files")) > 0 {
+		var err error
+
+		files, err = selectCodeFiles(c, currentDir, workDir)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	req, err := replayCodeRequest(currentDir, step, c.String("prompt"), files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	llmConfig := NewLLMConfigFromContext(c)
+	if err := llmConfig.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	req.Model = llmConfig.Model
+	req.EditFormat = editFormat
+	req.DryRun = c.Bool("dry-run")
+	req.PerFile = c.Bool("per-file")
+	req.AllowDirty = c.Bool("allow-dirty")
+	req.AllowUntracked = c.Bool("allow-untracked")
+	req.Jobs = 1 // With --per-file the files are replayed one at a time
+	req.RepoMapTokens = defaultRepoMapTokens
+	req.Budget = newTokenBudget(c.Context, llmConfig, req, false)
+
+	llm, err := llmConfig.BuildLLM()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return req, llm, nil
+}
+
+// replayCodeRequest rebuilds the request of a recorded step. A non-empty prompt or list
+// of absolute file paths replaces the recorded one; recorded symbols are only kept
+// with the recorded files.
+func replayCodeRequest(currentDir string, step *session.Step, prompt string, files []string) (*codeRequest, error) {
+	if err := checkReplayable(step); err != nil {
+		return nil, err
+	}
+
+	req := &codeRequest{
+		CurrentDir:   currentDir,
+		Prompt:       step.Command.Prompt,
+		AbsFilePaths: step.Command.Files,
+		ContextFiles: step.Command.ContextFiles,
+	}
+
+	if prompt != "" {
+		req.Prompt = prompt
+	}
+
+	if len(files) > 0 {
+		req.AbsFilePaths = files
+		return req, nil
+	}
+
+	for _, file := range req.AbsFilePaths {
+		if _, err := isValidFilePath(currentDir, file); err != nil {
+			return nil, err
+		}
+	}
+
+	symbols, absFilePaths, err := resolveSymbols(step.Command.Symbols, req.AbsFilePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Symbols, req.AbsFilePaths = symbols, absFilePaths
+
+	return req, nil
+}
+
+// checkReplayable refuses steps that didn't come from a prompt changing files.
+func checkReplayable(step *session.Step) error {
+	switch step.Type {
+	case "", session.StepTypeCode:
+		return nil
+	default:
+		return fmt.Errorf("%w: step %d is a %s step", ErrStepNotReplayable, step.ID, step.Type)
+	}
+}
+
+// reviseLastStep replays the last step of the session with the request, for ca code --revise.
+func reviseLastStep(ctx context.Context, llm gollm.LLM, currentSession *session.Session, req *codeRequest) error {
+	if len(currentSession.Steps) == 0 {
+		return ErrNoStepToRevise
+	}
+
+	step := currentSession.Steps[len(currentSession.Steps)-1]
+
+	return replayStep(ctx, llm, currentSession, step, req, &rollbackRequest{StepID: step.ID})
+}
+
+// replayStep restores the files of the step to their content from before it, runs the
+// request again and replaces the result of the step in place. The previous attempt is
+// kept in the step's history. When the llm fails the files are put back as they were.
+// A dry run previews the replay from the restored content without writing files.
+// Like a new step, a replay refuses untracked or dirty files unless they are allowed.
+func replayStep(ctx context.Context, llm gollm.LLM, currentSession *session.Session,
+	step *session.Step, req *codeRequest, restore *rollbackRequest) error {
+	blobStore := session.NewBlobStore(req.CurrentDir)
+
+	actions, err := planReplayRestore(blobStore, currentSession, step, req.CurrentDir, restore)
+	if err != nil {
+		return err
+	}
+
+	if req.DryRun {
+		return previewReplay(ctx, llm, req, actions)
+	}
+
+	if err := checkFilesClean(currentSession, req); err != nil {
+		return err
+	}
+
+	snapshots, gitStates, err := rerunStep(ctx, llm, blobStore, req, actions)
+	if err != nil {
+		return err
+	}
+
+	previous := *step
+	previous.History = nil
+
+	step.History = append(step.History, &previous)
+	step.Command = newCommand(req)
+	step.Timestamp = time.Now()
+	step.FilesDiff = session.NewFilesDiff(snapshots)
+	step.Git = gitStates
+	step.Verification = nil
+	step.SubSteps = nil
+
+	if err := session.SaveCurrentSession(req.CurrentDir, currentSession); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToSaveSession, err)
+	}
+
+	fmt.Printf("✅ Replayed step %d\n", step.ID)
+
+	if req.Verify || req.FixUntilGreen {
+		return verifyStep(ctx, llm, currentSession, step, req)
+	}
+
+	return nil
+}
+
+// previewReplay prints the changes a replay would make. The llm is sent the restored
+// content from memory so the files stay as they are.
+func previewReplay(ctx context.Context, llm gollm.LLM, req *codeRequest, actions []*rollbackAction) error {
+	req.Contents = replayContents(actions)
+
+	modifications, err := modifyCode(ctx, llm, req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAIProcessingFailed, err)
+	}
+
+	printModifications(modifications)
+
+	return nil
+}
+
+// rerunStep restores the files of the step, runs the request again and writes its changes.
+// When the llm or the write fails the files are put back as they were.
+func rerunStep(ctx context.Context, llm gollm.LLM, blobStore *session.BlobStore, req *codeRequest,
+	actions []*rollbackAction) ([]session.FileSnapshot, session.Git, error) {
+	restored, err := applyRollback(blobStore, actions)
+	if err != nil {
+		return nil, session.Git{}, err
+	}
+
+	modifications, err := modifyCode(ctx, llm, req)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrAIProcessingFailed, err)
+		return nil, session.Git{}, errors.Join(err, undoRestore(blobStore, req.CurrentDir, restored))
+	}
+
+	snapshots, gitStates, err := writeStepChanges(req.CurrentDir, modifications)
+	if err != nil {
+		return nil, session.Git{}, errors.Join(err, undoRestore(blobStore, req.CurrentDir, restored))
+	}
+
+	return snapshots, gitStates, nil
+}
+
+// planReplayRestore works out the content the files of the step had before it, without
+// writing anything.
+func planReplayRestore(blobStore *session.BlobStore, currentSession *session.Session, step *session.Step,
+	currentDir string, restore *rollbackRequest) ([]*rollbackAction, error) {
+	if err := checkReplayable(step); err != nil {
+		return nil, err
+	}
+
+	if rollbackStep := currentSession.RollbackStepFor(step.ID); rollbackStep != nil {
+		return nil, fmt.Errorf("%w: by step %d", ErrStepAlreadyRolledBack, rollbackStep.ID)
+	}
+
+	return planRollback(blobStore, currentDir, step, restore)
+}
+
+// replayContents returns the planned content of each file by absolute path, with nil for
+// files the restore removes.
+func replayContents(actions []*rollbackAction) map[string][]byte {
+	contents := make(map[string][]byte, len(actions))
+
+	for _, action := range actions {
+		if action.remove {
+			contents[action.absPath] = nil
+			continue
+		}
+
+		contents[action.absPath] = append([]byte{}, action.content...)
+	}
+
+	return contents
+}
+
+// undoRestore puts back the content the files had before they were restored for a replay.
+func undoRestore(blobStore *session.BlobStore, currentDir string, restored []session.FileSnapshot) error {
+	tx := filetx.New()
+
+	for _, snapshot := range restored {
+		absPath, err := isValidFilePath(currentDir, filepath.Join(currentDir, snapshot.Path))
+		if err != nil {
+			return err
+		}
+
+		if snapshot.PreHash == "" {
+			tx.Delete(absPath)
+			continue
+		}
+
+		content, err := blobStore.Get(snapshot.PreHash)
+		if err != nil {
+			return err
+		}
+
+		tx.Write(absPath, content)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToRestoreFile, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// setupReplayStep records a step that changed main.go from "a b c" to "a B c" with a prompt.
+func setupReplayStep(t *testing.T) (string, *session.Session) {
+	sessionDir := setupStep(t, "a\nb\nc\n", "a\nB\nc\n")
+
+	currentSession, err := session.LoadCurrentSession(sessionDir)
+	assert.NilError(t, err)
+
+	currentSession.Steps[0].Command = session.Command{
+		Prompt: "upper case b",
+		Files:  []string{filepath.Join(sessionDir, "main.go")},
+	}
+	assert.NilError(t, session.SaveCurrentSession(sessionDir, currentSession))
+
+	return sessionDir, currentSession
+}
+
+// replaceB answers with a block changing the b line of the original content.
+func replaceB(replacement string) *fakeLLM {
+	return &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "FILE: main.go\n<<<<<<< SEARCH\nb\n=======\n" + replacement + "\n>>>>>>> REPLACE\n", nil
+	}}
+}
+
+// TestReplayStep_ReplacesResult verifies the step is re-run from its pre-state and
+// replaced in place, keeping the previous attempt in its history.
+func TestReplayStep_ReplacesResult(t *testing.T) {
+	sessionDir, currentSession := setupReplayStep(t)
+	step := currentSession.Steps[0]
+	preHash := step.FilesDiff.Snapshots[0].PreHash
+
+	req, err := cmd.ReplayCodeRequest(sessionDir, step, "rename b", nil)
+	assert.NilError(t, err)
+
+	req.EditFormat = cmd.EditFormatSearchReplace
+	fake := replaceB("bee")
+
+	err = cmd.ReplayStep(context.Background(), fake, currentSession, step, req, &cmd.RollbackRequest{StepID: 1})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(fake.prompts[0], "Requested change: rename b"))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(sessionDir, "main.go")), "a\nbee\nc\n"))
+
+	saved, err := session.LoadCurrentSession(sessionDir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(saved.Steps, 1))
+
+	replayed := saved.Steps[0]
+	assert.Assert(t, cmp.Equal(replayed.Command.Prompt, "rename b"))
+	assert.Assert(t, cmp.Equal(replayed.FilesDiff.Snapshots[0].PreHash, preHash))
+	assert.Assert(t, cmp.Len(replayed.History, 1))
+	assert.Assert(t, cmp.Equal(replayed.History[0].Command.Prompt, "upper case b"))
+}
+
+// TestReplayStep_FailureKeepsFiles verifies the files are put back when the llm fails.
+func TestReplayStep_FailureKeepsFiles(t *testing.T) {
+	sessionDir, currentSession := setupReplayStep(t)
+	step := currentSession.Steps[0]
+
+	req, err := cmd.ReplayCodeRequest(sessionDir, step, "", nil)
+	assert.NilError(t, err)
+
+	req.EditFormat = cmd.EditFormatSearchReplace
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) { return "", errFakeLLM }}
+
+	err = cmd.ReplayStep(context.Background(), fake, currentSession, step, req, &cmd.RollbackRequest{StepID: 1})
+	assert.Assert(t, errors.Is(err, cmd.ErrAIProcessingFailed), "Expected ErrAIProcessingFailed, got: %v", err)
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(sessionDir, "main.go")), "a\nB\nc\n"))
+
+	saved, err := session.LoadCurrentSession(sessionDir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(saved.Steps[0].History, 0))
+}
+
+// TestReplayStep_DryRunLeavesFiles verifies a dry run sends the content from before the
+// step without ever writing it to disk.
+func TestReplayStep_DryRunLeavesFiles(t *testing.T) {
+	sessionDir, currentSession := setupReplayStep(t)
+	step := currentSession.Steps[0]
+	mainPath := filepath.Join(sessionDir, "main.go")
+
+	req, err := cmd.ReplayCodeRequest(sessionDir, step, "", nil)
+	assert.NilError(t, err)
+
+	req.EditFormat = cmd.EditFormatSearchReplace
+	req.DryRun = true
+
+	onDisk := ""
+	fake := &fakeLLM{generate: func(ctx context.Context, prompt string) (string, error) {
+		onDisk = readFile(t, mainPath)
+		return replaceB("bee").generate(ctx, prompt)
+	}}
+
+	err = cmd.ReplayStep(context.Background(), fake, currentSession, step, req, &cmd.RollbackRequest{StepID: 1})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(fake.prompts[0], "a\nb\nc\n"))
+	assert.Assert(t, cmp.Equal(onDisk, "a\nB\nc\n"))
+	assert.Assert(t, cmp.Equal(readFile(t, mainPath), "a\nB\nc\n"))
+
+	saved, err := session.LoadCurrentSession(sessionDir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(saved.Steps[0].History, 0))
+}
+
+// TestCode_Revise verifies --revise replaces the last step instead of adding one.
+func TestCode_Revise(t *testing.T) {
+	sessionDir, _ := setupReplayStep(t)
+
+	err := cmd.ExecuteCodeCommand(context.Background(), replaceB("B!"), &cmd.CodeRequest{
+		CurrentDir: sessionDir, Prompt: "shout b", AbsFilePaths: []string{filepath.Join(sessionDir, "main.go")},
+		EditFormat: cmd.EditFormatSearchReplace, Revise: true,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(sessionDir, "main.go")), "a\nB!\nc\n"))
+
+	saved, err := session.LoadCurrentSession(sessionDir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(saved.Steps, 1))
+	assert.Assert(t, cmp.Equal(saved.Steps[0].Command.Prompt, "shout b"))
+	assert.Assert(t, cmp.Len(saved.Steps[0].History, 1))
+}
@@ -5,19 +5,241 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/chrisrob11/codeassistant/internal/session"
 	cli "github.com/urfave/cli/v2"
 )
 
+// Review errors.
+var (
+	ErrInvalidStepRange = errors.New("invalid step range")
+)
+
+// reviewRequest holds the options for reviewing the session.
+type reviewRequest struct {
+	FromStep int
+	ToStep   int
+	File     string
+	Stat     bool
+	Color    bool
+}
+
 // ReviewCommand shows the session progress and diffs.
 func ReviewCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "review",
 		Usage: "Show session progress and diffs",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "step",
+				Usage: "Only show a single step",
+			},
+			&cli.IntFlag{
+				Name:  "from",
+				Usage: "First step to show",
+			},
+			&cli.IntFlag{
+				Name:  "to",
+				Usage: "Last step to show",
+			},
+			&cli.StringFlag{
+				Name:  "file",
+				Usage: "Only show changes to this file",
+			},
+			&cli.BoolFlag{
+				Name:  "stat",
+				Usage: "Show a summary of changed lines instead of full diffs",
+			},
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: "Disable colored output",
+			},
+		},
 		Action: func(c *cli.Context) error {
-			fmt.Println("Reviewing session changes...")
-			return nil
+			currentDir, _, err := findCurrentDir()
+			if err != nil {
+				return err
+			}
+
+			req := &reviewRequest{
+				FromStep: c.Int("from"),
+				ToStep:   c.Int("to"),
+				Stat:     c.Bool("stat"),
+				Color:    !c.Bool("no-color") && isTerminal(os.Stdout),
+			}
+
+			if c.IsSet("step") {
+				req.FromStep, req.ToStep = c.Int("step"), c.Int("step")
+			}
+
+			if req.ToStep != 0 && req.FromStep > req.ToStep {
+				return fmt.Errorf("%w: %d > %d", ErrInvalidStepRange, req.FromStep, req.ToStep)
+			}
+
+			if file := c.String("file"); file != "" {
+				absPath, err := isValidFilePath(currentDir, file)
+				if err != nil {
+					return err
+				}
+
+				req.File, err = filepath.Rel(currentDir, absPath)
+				if err != nil {
+					return fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+				}
+			}
+
+			return executeReviewCommand(os.Stdout, currentDir, req)
 		},
 	}
 }
+
+func executeReviewCommand(w io.Writer, currentDir string, req *reviewRequest) error {
+	currentSession, err := session.LoadCurrentSession(currentDir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToLoadSession, err)
+	}
+
+	fmt.Fprintf(w, "Session: %s (started %s)\n", currentSession.Name,
+		currentSession.CreatedAt.Format(time.RFC3339))
+
+	blobStore := session.NewBlobStore(currentDir)
+
+	for _, step := range currentSession.Steps {
+		if step.ID < req.FromStep || (req.ToStep != 0 && step.ID > req.ToStep) {
+			continue
+		}
+
+		if err := reviewStep(w, blobStore, fmt.Sprintf("Step %d", step.ID), step, req); err != nil {
+			return err
+		}
+
+		for _, subStep := range step.SubSteps {
+			label := fmt.Sprintf("Step %d.%d", step.ID, subStep.ID)
+			if err := reviewStep(w, blobStore, label, subStep, req); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// reviewStep writes the details of a step followed by its diffs or stats.
+func reviewStep(w io.Writer, blobStore *session.BlobStore, label string, step *session.Step, req *reviewRequest) error {
+	snapshots := make([]session.FileSnapshot, 0, len(step.FilesDiff.Snapshots))
+
+	for _, snapshot := range step.FilesDiff.Snapshots {
+		if req.File == "" || snapshot.Path == req.File {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	if req.File != "" && len(snapshots) == 0 {
+		return nil
+	}
+
+	writeStepHeader(w, label, step, req.Color)
+
+	switch {
+	case step.Summary != "":
+		fmt.Fprintf(w, "\n%s\n", indent(step.Summary, "  "))
+	case len(step.FilesDiff.Snapshots) == 0:
+		fmt.Fprintln(w, "  (no file snapshots recorded)")
+	}
+
+	for _, snapshot := range snapshots {
+		diff, err := snapshotDiff(blobStore, snapshot)
+		if err != nil {
+			return err
+		}
+
+		if req.Stat {
+			added, removed := diffStat(diff)
+			fmt.Fprintf(w, "  %s | +%d -%d\n", snapshot.Path, added, removed)
+
+			continue
+		}
+
+		if req.Color {
+			diff = colorizeDiff(diff)
+		}
+
+		fmt.Fprint(w, diff)
+	}
+
+	fmt.Fprintln(w)
+
+	return nil
+}
+
+// writeStepHeader writes the summary lines describing a step.
+func writeStepHeader(w io.Writer, title string, step *session.Step, color bool) {
+	if color {
+		title = colorBold + title + colorReset
+	}
+
+	stepType := step.Type
+	if stepType == "" {
+		stepType = session.StepTypeCode
+	}
+
+	fmt.Fprintf(w, "%s [%s] %s\n", title, stepType, step.Timestamp.Format(time.RFC3339))
+
+	if step.Command.Model != "" {
+		fmt.Fprintf(w, "  Model:  %s\n", step.Command.Model)
+	}
+
+	fmt.Fprintf(w, "  Prompt: %s\n", step.Command.Prompt)
+
+	if step.Verification != nil {
+		fmt.Fprintf(w, "  Verify: %s\n", step.Verification.Status)
+	}
+
+	if len(step.History) > 0 {
+		fmt.Fprintf(w, "  Replays: %d earlier attempts kept in history\n", len(step.History))
+	}
+
+	files := make([]string, 0, len(step.FilesDiff.Snapshots))
+	for _, snapshot := range step.FilesDiff.Snapshots {
+		files = append(files, snapshot.Path)
+	}
+
+	if len(files) == 0 {
+		files = step.Command.Files
+	}
+
+	fmt.Fprintf(w, "  Files:  %s\n", strings.Join(files, ", "))
+
+	if len(step.Command.ContextFiles) > 0 {
+		fmt.Fprintf(w, "  Context: %s\n", strings.Join(step.Command.ContextFiles, ", "))
+	}
+}
+
+// isTerminal reports whether the file is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// indent prefixes every non-empty line of text.
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
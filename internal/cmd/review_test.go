@@ -0,0 +1,92 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+)
+
+// TestReview_ShowsDiff verifies that a step is rendered with its unified diff.
+func TestReview_ShowsDiff(t *testing.T) {
+	sessionDir := setupStep(t, "a\nb\nc\n", "a\nB\nc\n")
+
+	var out bytes.Buffer
+	err := cmd.ExecuteReviewCommand(&out, sessionDir, &cmd.ReviewRequest{})
+	assert.NilError(t, err)
+
+	output := out.String()
+	assert.Assert(t, strings.Contains(output, "Step 1 [code]"), output)
+	assert.Assert(t, strings.Contains(output, "--- a/main.go\n+++ b/main.go\n"), output)
+	assert.Assert(t, strings.Contains(output, "-b\n+B\n"), output)
+}
+
+// TestReview_Stat verifies the --stat summary and file filtering.
+func TestReview_Stat(t *testing.T) {
+	sessionDir := setupStep(t, "a\nb\nc\n", "a\nB\nc\nd\n")
+
+	var out bytes.Buffer
+	err := cmd.ExecuteReviewCommand(&out, sessionDir, &cmd.ReviewRequest{Stat: true})
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(out.String(), "main.go | +2 -1"), out.String())
+
+	out.Reset()
+	err = cmd.ExecuteReviewCommand(&out, sessionDir, &cmd.ReviewRequest{File: "other.go"})
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(out.String(), "Step 1"), out.String())
+}
+
+// TestReview_StatCountsDashLines verifies changed lines starting with -- or ++ are counted.
+func TestReview_StatCountsDashLines(t *testing.T) {
+	sessionDir := setupStep(t, "a\n-- old comment\n", "a\n---\n++counter\n")
+
+	var out bytes.Buffer
+	err := cmd.ExecuteReviewCommand(&out, sessionDir, &cmd.ReviewRequest{Stat: true})
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(out.String(), "main.go | +2 -1"), out.String())
+}
+
+// TestReview_CreatedFile verifies a created file shows exactly its own lines.
+func TestReview_CreatedFile(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n"})
+	assert.NilError(t, session.StartSession(&session.StartSessionRequest{Name: "review", Dir: dir}))
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "FILE: new.go\n<<<<<<< SEARCH\n=======\npackage a\n\n>>>>>>> REPLACE\n", nil
+	}}
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "add new.go", AbsFilePaths: paths, EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+
+	var out bytes.Buffer
+	assert.NilError(t, cmd.ExecuteReviewCommand(&out, dir, &cmd.ReviewRequest{}))
+	assert.Assert(t, strings.Contains(out.String(), "+++ b/new.go\n@@ -0,0 +1,2 @@\n+package a\n+\n"), out.String())
+
+	out.Reset()
+	assert.NilError(t, cmd.ExecuteReviewCommand(&out, dir, &cmd.ReviewRequest{Stat: true}))
+	assert.Assert(t, strings.Contains(out.String(), "new.go | +2 -0"), out.String())
+}
+
+// TestReview_NoTrailingNewline verifies a last line without a line ending is marked like git does.
+func TestReview_NoTrailingNewline(t *testing.T) {
+	sessionDir := setupStep(t, "a\nb", "a\nc")
+
+	var out bytes.Buffer
+	assert.NilError(t, cmd.ExecuteReviewCommand(&out, sessionDir, &cmd.ReviewRequest{}))
+	assert.Assert(t, strings.Contains(out.String(),
+		"-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"), out.String())
+
+	out.Reset()
+	assert.NilError(t, cmd.ExecuteReviewCommand(&out, sessionDir, &cmd.ReviewRequest{Stat: true}))
+	assert.Assert(t, strings.Contains(out.String(), "main.go | +1 -1"), out.String())
+}
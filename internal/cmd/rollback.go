@@ -5,11 +5,46 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/chrisrob11/codeassistant/internal/filetx"
+	"github.com/chrisrob11/codeassistant/internal/session"
 	cli "github.com/urfave/cli/v2"
 )
 
+// Rollback errors.
+var (
+	ErrStepMustBeSpecified   = errors.New("failed as step not specified")
+	ErrStepAlreadyRolledBack = errors.New("step has already been rolled back")
+	ErrStepHasNoSnapshots    = errors.New("step has no file snapshots to restore")
+	ErrRollbackConflict      = errors.New("files were changed after the step")
+	ErrMergeFailed           = errors.New("failed to merge file")
+	ErrFailedToRestoreFile   = errors.New("failed to restore file")
+)
+
+// rollbackRequest holds the options for rolling back a step.
+type rollbackRequest struct {
+	StepID int
+	Force  bool
+	Merge  bool
+}
+
+// rollbackAction is the planned restoration of a single file.
+type rollbackAction struct {
+	snapshot    session.FileSnapshot
+	absPath     string
+	currentHash string
+	content     []byte
+	remove      bool
+	conflicted  bool
+}
+
 // RollbackCommand undoes a specific AI-modified step.
 func RollbackCommand() *cli.Command {
 	return &cli.Command{
@@ -20,10 +55,267 @@ func RollbackCommand() *cli.Command {
 				Name:  "step",
 				Usage: "Specify the step to roll back",
 			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Restore files even if they were edited after the step",
+			},
+			&cli.BoolFlag{
+				Name:  "merge",
+				Usage: "Three-way merge files that were edited after the step",
+			},
 		},
 		Action: func(c *cli.Context) error {
-			fmt.Println("Rolling back step:", c.Int("step"))
-			return nil
+			currentDir, _, err := findCurrentDir()
+			if err != nil {
+				return err
+			}
+
+			if c.Int("step") <= 0 {
+				return ErrStepMustBeSpecified
+			}
+
+			return executeRollbackCommand(currentDir, &rollbackRequest{
+				StepID: c.Int("step"),
+				Force:  c.Bool("force"),
+				Merge:  c.Bool("merge"),
+			})
 		},
 	}
 }
+
+func executeRollbackCommand(currentDir string, req *rollbackRequest) error {
+	currentSession, err := session.LoadCurrentSession(currentDir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToLoadSession, err)
+	}
+
+	step, err := currentSession.FindStep(req.StepID)
+	if err != nil {
+		return err
+	}
+
+	if rollbackStep := currentSession.RollbackStepFor(step.ID); rollbackStep != nil && !req.Force {
+		return fmt.Errorf("%w: by step %d", ErrStepAlreadyRolledBack, rollbackStep.ID)
+	}
+
+	if len(step.CombinedSnapshots()) == 0 {
+		return fmt.Errorf("%w: %d", ErrStepHasNoSnapshots, step.ID)
+	}
+
+	blobStore := session.NewBlobStore(currentDir)
+
+	actions, err := planRollback(blobStore, currentDir, step, req)
+	if err != nil {
+		return err
+	}
+
+	gitPre, err := captureGitState(currentDir)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := applyRollback(blobStore, actions)
+	if err != nil {
+		return err
+	}
+
+	gitPost, err := captureGitState(currentDir)
+	if err != nil {
+		return err
+	}
+
+	files := make([]string, 0, len(actions))
+	for _, action := range actions {
+		files = append(files, action.absPath)
+
+		if action.conflicted {
+			fmt.Printf("⚠️  Merge conflicts left in %s\n", action.snapshot.Path)
+		}
+	}
+
+	// Record the rollback so it can be reviewed and undone like any other step
+	currentSession.Steps = append(currentSession.Steps, &session.Step{
+		ID:         currentSession.NextStepID(),
+		Type:       session.StepTypeRollback,
+		RollbackOf: step.ID,
+		Command:    session.Command{Prompt: fmt.Sprintf("rollback step %d", step.ID), Files: files},
+		Timestamp:  time.Now(),
+		FilesDiff:  session.NewFilesDiff(snapshots),
+		Git:        session.Git{Pre: gitPre, Post: gitPost},
+	})
+
+	if err := session.SaveCurrentSession(currentDir, currentSession); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToSaveSession, err)
+	}
+
+	fmt.Printf("✅ Rolled back step %d\n", step.ID)
+
+	return nil
+}
+
+// planRollback works out the content to restore for every file touched by the step
+// without writing anything, so a conflict leaves the tree untouched.
+func planRollback(
+	blobStore *session.BlobStore, currentDir string, step *session.Step, req *rollbackRequest,
+) ([]*rollbackAction, error) {
+	snapshots := step.CombinedSnapshots()
+	actions := make([]*rollbackAction, 0, len(snapshots))
+	conflicts := []string{}
+
+	for _, snapshot := range snapshots {
+		// Files sent with the step but left alone have nothing to restore, and may since
+		// have been edited by hand
+		if snapshot.PreHash == snapshot.PostHash {
+			continue
+		}
+
+		absPath, err := isValidFilePath(currentDir, filepath.Join(currentDir, snapshot.Path))
+		if err != nil {
+			return nil, err
+		}
+
+		currentHash, err := blobStore.SnapshotFile(absPath)
+		if err != nil {
+			return nil, err
+		}
+
+		action := &rollbackAction{snapshot: snapshot, absPath: absPath, currentHash: currentHash}
+
+		switch {
+		case currentHash == snapshot.PostHash || req.Force:
+			err = action.restorePre(blobStore)
+		case req.Merge && snapshot.PreHash != "" && snapshot.PostHash != "" && currentHash != "":
+			err = action.mergePre(blobStore, step.ID)
+		default:
+			conflicts = append(conflicts, snapshot.Path)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		actions = append(actions, action)
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %s (use --merge or --force)", ErrRollbackConflict, strings.Join(conflicts, ", "))
+	}
+
+	return actions, nil
+}
+
+// restorePre sets the action to put back the content from before the step.
+func (a *rollbackAction) restorePre(blobStore *session.BlobStore) error {
+	if a.snapshot.PreHash == "" {
+		a.remove = true
+		return nil
+	}
+
+	content, err := blobStore.Get(a.snapshot.PreHash)
+	if err != nil {
+		return err
+	}
+
+	a.content = content
+
+	return nil
+}
+
+// mergePre sets the action to the three-way merge of the current content with
+// the pre-step content, using the post-step content as the common base.
+func (a *rollbackAction) mergePre(blobStore *session.BlobStore, stepID int) error {
+	current, err := os.ReadFile(a.absPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToReadFile, err)
+	}
+
+	base, err := blobStore.Get(a.snapshot.PostHash)
+	if err != nil {
+		return err
+	}
+
+	pre, err := blobStore.Get(a.snapshot.PreHash)
+	if err != nil {
+		return err
+	}
+
+	merged, conflicted, err := mergeThreeWay(current, base, pre, fmt.Sprintf("before step %d", stepID))
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrMergeFailed, a.snapshot.Path, err)
+	}
+
+	a.content = merged
+	a.conflicted = conflicted
+
+	return nil
+}
+
+// applyRollback writes the planned content as one transaction and returns the snapshots of the change.
+func applyRollback(blobStore *session.BlobStore, actions []*rollbackAction) ([]session.FileSnapshot, error) {
+	snapshots := make([]session.FileSnapshot, 0, len(actions))
+	tx := filetx.New()
+
+	for _, action := range actions {
+		snapshot := session.FileSnapshot{Path: action.snapshot.Path, PreHash: action.currentHash}
+
+		if action.remove {
+			tx.Delete(action.absPath)
+			snapshots = append(snapshots, snapshot)
+
+			continue
+		}
+
+		postHash, err := blobStore.Put(action.content)
+		if err != nil {
+			return nil, err
+		}
+
+		tx.Write(action.absPath, action.content)
+
+		snapshot.PostHash = postHash
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToRestoreFile, err)
+	}
+
+	return snapshots, nil
+}
+
+// mergeThreeWay merges the changes between base and other into current using git merge-file.
+// It reports whether conflict markers were left in the result.
+func mergeThreeWay(current, base, other []byte, otherLabel string) ([]byte, bool, error) {
+	tempDir, err := os.MkdirTemp("", "ca-merge-")
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer os.RemoveAll(tempDir)
+
+	paths := make([]string, 0, 3)
+
+	for i, content := range [][]byte{current, base, other} {
+		path := filepath.Join(tempDir, fmt.Sprintf("%d", i))
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			return nil, false, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	// nolint:gosec // Why: arguments are temp files created above
+	out, err := exec.Command("git", "merge-file", "-p",
+		"-L", "current", "-L", "step", "-L", otherLabel,
+		paths[0], paths[1], paths[2]).Output()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		// A positive exit code is the number of conflicts
+		return out, true, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return out, false, nil
+}
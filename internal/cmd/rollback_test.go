@@ -0,0 +1,149 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// setupStep starts a session and records a step that changed main.go from pre to post.
+func setupStep(t *testing.T, pre, post string) string {
+	sessionDir := t.TempDir()
+	assert.NilError(t, session.StartSession(&session.StartSessionRequest{Name: "rollback", Dir: sessionDir}))
+
+	store := session.NewBlobStore(sessionDir)
+	preHash, err := store.Put([]byte(pre))
+	assert.NilError(t, err)
+	postHash, err := store.Put([]byte(post))
+	assert.NilError(t, err)
+
+	assert.NilError(t, os.WriteFile(filepath.Join(sessionDir, "main.go"), []byte(post), 0600))
+
+	currentSession, err := session.LoadCurrentSession(sessionDir)
+	assert.NilError(t, err)
+
+	currentSession.Steps = append(currentSession.Steps, &session.Step{
+		ID:        1,
+		Type:      session.StepTypeCode,
+		Timestamp: time.Now(),
+		FilesDiff: session.FilesDiff{Snapshots: []session.FileSnapshot{
+			{Path: "main.go", PreHash: preHash, PostHash: postHash},
+		}},
+	})
+	assert.NilError(t, session.SaveCurrentSession(sessionDir, currentSession))
+
+	return sessionDir
+}
+
+func readFile(t *testing.T, path string) string {
+	// nolint:gosec // Why: test code
+	content, err := os.ReadFile(path)
+	assert.NilError(t, err)
+
+	return string(content)
+}
+
+// TestRollback_RestoresPreContent verifies an untouched file is restored and the rollback recorded.
+func TestRollback_RestoresPreContent(t *testing.T) {
+	sessionDir := setupStep(t, "a\nb\nc\n", "a\nB\nc\n")
+
+	err := cmd.ExecuteRollbackCommand(sessionDir, &cmd.RollbackRequest{StepID: 1})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(sessionDir, "main.go")), "a\nb\nc\n"))
+
+	currentSession, err := session.LoadCurrentSession(sessionDir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(currentSession.Steps, 2))
+	assert.Assert(t, cmp.Equal(currentSession.Steps[1].Type, session.StepTypeRollback))
+	assert.Assert(t, cmp.Equal(currentSession.Steps[1].RollbackOf, 1))
+
+	err = cmd.ExecuteRollbackCommand(sessionDir, &cmd.RollbackRequest{StepID: 1})
+	assert.Assert(t, errors.Is(err, cmd.ErrStepAlreadyRolledBack), "Expected ErrStepAlreadyRolledBack, got: %v", err)
+}
+
+// TestRollback_DetectsConflict ensures hand edits after the step are not overwritten.
+func TestRollback_DetectsConflict(t *testing.T) {
+	sessionDir := setupStep(t, "a\nb\nc\n", "a\nB\nc\n")
+	filePath := filepath.Join(sessionDir, "main.go")
+	assert.NilError(t, os.WriteFile(filePath, []byte("a\nB\nc\nd\n"), 0600))
+
+	err := cmd.ExecuteRollbackCommand(sessionDir, &cmd.RollbackRequest{StepID: 1})
+	assert.Assert(t, errors.Is(err, cmd.ErrRollbackConflict), "Expected ErrRollbackConflict, got: %v", err)
+	assert.Assert(t, cmp.Equal(readFile(t, filePath), "a\nB\nc\nd\n"))
+}
+
+// TestRollback_MergesHandEdits verifies that --merge keeps later edits while undoing the step.
+func TestRollback_MergesHandEdits(t *testing.T) {
+	sessionDir := setupStep(t, "a\nb\nc\nd\ne\n", "a\nB\nc\nd\ne\n")
+	filePath := filepath.Join(sessionDir, "main.go")
+	assert.NilError(t, os.WriteFile(filePath, []byte("a\nB\nc\nd\nE\n"), 0600))
+
+	err := cmd.ExecuteRollbackCommand(sessionDir, &cmd.RollbackRequest{StepID: 1, Merge: true})
+	assert.NilError(t, err)
+
+	merged := readFile(t, filePath)
+	assert.Assert(t, !strings.Contains(merged, "<<<<<<<"), "Unexpected conflict markers: %s", merged)
+	assert.Assert(t, cmp.Equal(merged, "a\nb\nc\nd\nE\n"))
+}
+
+// TestRollback_CreatedAndDeletedFiles verifies created files are removed and deleted files restored.
+func TestRollback_CreatedAndDeletedFiles(t *testing.T) {
+	sessionDir := setupStep(t, "a\n", "a\n")
+	store := session.NewBlobStore(sessionDir)
+
+	oldHash, err := store.Put([]byte("old\n"))
+	assert.NilError(t, err)
+	newHash, err := store.Put([]byte("new\n"))
+	assert.NilError(t, err)
+
+	assert.NilError(t, os.WriteFile(filepath.Join(sessionDir, "new.go"), []byte("new\n"), 0600))
+
+	currentSession, err := session.LoadCurrentSession(sessionDir)
+	assert.NilError(t, err)
+
+	currentSession.Steps[0].FilesDiff = session.NewFilesDiff([]session.FileSnapshot{
+		{Path: "new.go", PostHash: newHash},
+		{Path: "old.go", PreHash: oldHash},
+	})
+	assert.NilError(t, session.SaveCurrentSession(sessionDir, currentSession))
+
+	assert.NilError(t, cmd.ExecuteRollbackCommand(sessionDir, &cmd.RollbackRequest{StepID: 1}))
+
+	_, err = os.Stat(filepath.Join(sessionDir, "new.go"))
+	assert.Assert(t, os.IsNotExist(err), "Expected created file to be removed.")
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(sessionDir, "old.go")), "old\n"))
+}
+
+// TestRollback_SkipsUnchangedFiles verifies files the step was given but left alone are
+// neither reported as conflicts nor reverted when edited by hand afterwards.
+func TestRollback_SkipsUnchangedFiles(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	assert.NilError(t, session.StartSession(&session.StartSessionRequest{Name: "rollback", Dir: dir}))
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "FILE: a.go\n<<<<<<< SEARCH\npackage a\n=======\npackage a // changed\n>>>>>>> REPLACE\n", nil
+	}}
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "change a", AbsFilePaths: paths, EditFormat: cmd.EditFormatSearchReplace,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(paths[1], []byte("package b // human\n"), 0600))
+
+	assert.NilError(t, cmd.ExecuteRollbackCommand(dir, &cmd.RollbackRequest{StepID: 1}))
+	assert.Assert(t, cmp.Equal(readFile(t, paths[0]), "package a\n"))
+	assert.Assert(t, cmp.Equal(readFile(t, paths[1]), "package b // human\n"))
+}
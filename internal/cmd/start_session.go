@@ -5,6 +5,8 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/chrisrob11/codeassistant/internal/session"
@@ -23,14 +25,66 @@ func NewSessionCommand() *cli.Command {
 				Usage:    "Name of the session to start",
 				Required: true,
 			},
+			&cli.BoolFlag{
+				Name:  "branch",
+				Usage: "Make the session's changes on a new branch, merged back by end-session",
+			},
+			&cli.StringFlag{
+				Name:  "branch-name",
+				Usage: "Name of the session branch, ca/<session name> when not set",
+			},
+			&cli.StringFlag{
+				Name:  "worktree",
+				Usage: "Check the session branch out in a separate work tree in this directory",
+			},
 		},
 		Action: func(c *cli.Context) error {
-			currentDir, err := os.Getwd()
+			currentDir, _, err := findCurrentDir()
 			if err != nil {
 				return err
 			}
 
-			return session.StartSession(&session.StartSessionRequest{Dir: currentDir, Name: c.String("name")})
+			req := &session.StartSessionRequest{Dir: currentDir, Name: c.String("name")}
+			if !c.Bool("branch") && c.String("worktree") == "" {
+				return session.StartSession(req)
+			}
+
+			return startIsolatedSession(currentDir, req, &isolationRequest{
+				Name:     req.Name,
+				Branch:   c.String("branch-name"),
+				Worktree: c.String("worktree"),
+			})
 		},
 	}
 }
+
+// startIsolatedSession creates the session branch, or work tree, and starts the session
+// on it. The branch is removed again when the session can't be started.
+func startIsolatedSession(currentDir string, req *session.StartSessionRequest, isolationReq *isolationRequest) error {
+	// Checked first so an existing session doesn't leave a branch behind
+	if _, err := os.Stat(session.BuildCurrentSessionFilePath(currentDir)); err == nil && isolationReq.Worktree == "" {
+		return session.ErrSessionExists
+	}
+
+	isolation, err := isolateSession(currentDir, isolationReq)
+	if err != nil {
+		return err
+	}
+
+	req.Isolation = isolation
+	if isolation.Worktree != "" {
+		req.Dir = isolation.Worktree
+	}
+
+	if err := session.StartSession(req); err != nil {
+		return errors.Join(err, undoIsolation(currentDir, isolation))
+	}
+
+	fmt.Printf("🌿 Session changes are made on branch %s, based on %s\n", isolation.Branch, isolation.BaseBranch)
+
+	if isolation.Worktree != "" {
+		fmt.Printf("📂 Run ca commands for the session in %s\n", isolation.Worktree)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"github.com/teilomillet/gollm"
+)
+
+// Summary errors.
+var (
+	ErrSummaryOptionWithoutSummary = errors.New("--output and --store-session require --summary")
+	ErrFailedToWriteSummary        = errors.New("failed to write summary")
+	ErrSummaryOutputIsSource       = errors.New("summary output would overwrite a file being analyzed")
+)
+
+// Completion limit chosen for an analysis when llm-max-tokens isn't set.
+const summaryOutputTokens = 2048
+
+// executeSummary asks the llm to analyze the files and reports the analysis. Source
+// files are never written; the analysis is printed or written to the output file and
+// optionally stored as a summary step.
+func executeSummary(ctx context.Context, llm gollm.LLM, req *codeRequest) error {
+	if err := checkSummaryOutput(req); err != nil {
+		return err
+	}
+
+	var currentSession *session.Session
+
+	// Loaded first so a missing session fails before the llm call
+	if req.StoreSummary {
+		var err error
+
+		currentSession, err = session.LoadCurrentSession(req.CurrentDir)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrFailedToLoadSession, err)
+		}
+	}
+
+	analysis, err := summarizeCode(ctx, llm, req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAIProcessingFailed, err)
+	}
+
+	if req.OutputFile == "" {
+		fmt.Println(analysis)
+	} else if err := writeSummary(req.CurrentDir, req.OutputFile, analysis); err != nil {
+		return err
+	}
+
+	if currentSession == nil {
+		return nil
+	}
+
+	gitState, err := captureGitState(req.CurrentDir)
+	if err != nil {
+		return err
+	}
+
+	currentSession.Steps = append(currentSession.Steps, &session.Step{
+		ID:        currentSession.NextStepID(),
+		Type:      session.StepTypeSummary,
+		Command:   newCommand(req),
+		Timestamp: time.Now(),
+		FilesDiff: session.NewFilesDiff(nil),
+		Git:       session.Git{Pre: gitState, Post: gitState},
+		Summary:   analysis,
+	})
+
+	if err := session.SaveCurrentSession(req.CurrentDir, currentSession); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToSaveSession, err)
+	}
+
+	return nil
+}
+
+// summarizeCode returns the analysis of the request files. By default they are analyzed
+// together; with PerFile each file gets its own llm call and section of the analysis.
+func summarizeCode(ctx context.Context, llm gollm.LLM, req *codeRequest) (string, error) {
+	refs, err := loadReferences(req)
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]promptFile, 0, len(req.AbsFilePaths))
+	symbols := make(map[string][]string, len(req.AbsFilePaths))
+
+	for _, file := range req.AbsFilePaths {
+		relPath, err := filepath.Rel(req.CurrentDir, file)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+		}
+
+		// nolint:gosec //Why: files are validated within a specific path
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %v", ErrFailedToReadFile, relPath, err)
+		}
+
+		files = append(files, promptFile{Path: filepath.ToSlash(relPath), Content: string(content)})
+	}
+
+	for _, target := range req.Symbols {
+		symbols[target.File] = append(symbols[target.File], target.Symbol)
+	}
+
+	if !req.PerFile {
+		allSymbols := []string{}
+		for _, target := range req.Symbols {
+			allSymbols = append(allSymbols, target.Symbol)
+		}
+
+		return summarizeFiles(ctx, llm, req, "summary prompt", files, allSymbols, refs)
+	}
+
+	sections := make([]string, 0, len(files))
+
+	for i, file := range files {
+		analysis, err := summarizeFiles(ctx, llm, req, file.Path, files[i:i+1], symbols[req.AbsFilePaths[i]], refs)
+		if err != nil {
+			return "", err
+		}
+
+		sections = append(sections, fmt.Sprintf("## %s\n\n%s", file.Path, analysis))
+	}
+
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// summarizeFiles sends one summary prompt and returns the analysis.
+func summarizeFiles(
+	ctx context.Context, llm gollm.LLM, req *codeRequest, label string,
+	files []promptFile, symbols []string, refs *promptReferences,
+) (string, error) {
+	fullPrompt := buildSummaryPrompt(req.Prompt, files, symbols, refs)
+
+	// The length of an analysis doesn't follow from the input, so there is nothing to warn about
+	if err := req.Budget.check(label, fullPrompt, 0); err != nil {
+		return "", err
+	}
+
+	response, err := processWithLLM(ctx, llm, fullPrompt)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", label, err)
+	}
+
+	return strings.TrimSpace(response), nil
+}
+
+// checkSummaryOutput refuses an output path that is one of the files or context files
+// being analyzed, including through a symlink, since summary mode never writes source.
+func checkSummaryOutput(req *codeRequest) error {
+	if req.OutputFile == "" {
+		return nil
+	}
+
+	output := summaryOutputPath(req.CurrentDir, req.OutputFile)
+	outputInfo, statErr := os.Stat(output)
+
+	for _, file := range append(append([]string{}, req.AbsFilePaths...), req.ContextFiles...) {
+		if filepath.Clean(file) == output {
+			return fmt.Errorf("%w: %s", ErrSummaryOutputIsSource, req.OutputFile)
+		}
+
+		if statErr != nil {
+			continue
+		}
+
+		if info, err := os.Stat(file); err == nil && os.SameFile(outputInfo, info) {
+			return fmt.Errorf("%w: %s", ErrSummaryOutputIsSource, req.OutputFile)
+		}
+	}
+
+	return nil
+}
+
+// summaryOutputPath returns the output path, relative to the current directory unless absolute.
+func summaryOutputPath(currentDir, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(currentDir, path)
+	}
+
+	return filepath.Clean(path)
+}
+
+// writeSummary writes the analysis to path, relative to the current directory unless absolute.
+func writeSummary(currentDir, path, analysis string) error {
+	path = summaryOutputPath(currentDir, path)
+
+	if err := os.WriteFile(path, []byte(analysis+"\n"), 0600); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToWriteSummary, err)
+	}
+
+	fmt.Printf("Summary written to %s\n", path)
+
+	return nil
+}
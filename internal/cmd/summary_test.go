@@ -0,0 +1,103 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// TestSummary_WritesOutputAndStoresStep verifies an analysis leaves the files alone, is
+// written to the output file and is stored as a summary step.
+func TestSummary_WritesOutputAndStoresStep(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	assert.NilError(t, session.StartSession(&session.StartSessionRequest{Name: "summary", Dir: dir}))
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "\nNo bugs found.\n", nil
+	}}
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "review for bugs", AbsFilePaths: paths,
+		EditFormat: cmd.EditFormatSearchReplace, Summary: true, OutputFile: "review.md", StoreSummary: true,
+	})
+	assert.NilError(t, err)
+
+	assert.Assert(t, cmp.Len(fake.prompts, 1))
+	assert.Assert(t, cmp.Contains(fake.prompts[0], "You are reviewing these files: a.go, b.go."))
+	assert.Assert(t, cmp.Contains(fake.prompts[0], "Request: review for bugs"))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "review.md")), "No bugs found.\n"))
+	assert.Assert(t, cmp.Equal(readFile(t, paths[0]), "package a\n"))
+
+	currentSession, err := session.LoadCurrentSession(dir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(currentSession.Steps, 1))
+
+	step := currentSession.Steps[0]
+	assert.Assert(t, cmp.Equal(step.Type, session.StepTypeSummary))
+	assert.Assert(t, cmp.Equal(step.Summary, "No bugs found."))
+	assert.Assert(t, cmp.Equal(step.Command.Prompt, "review for bugs"))
+	assert.Assert(t, cmp.Len(step.FilesDiff.Snapshots, 0))
+
+	var out bytes.Buffer
+	assert.NilError(t, cmd.ExecuteReviewCommand(&out, dir, &cmd.ReviewRequest{}))
+	assert.Assert(t, cmp.Contains(out.String(), "Step 1 [summary]"))
+	assert.Assert(t, cmp.Contains(out.String(), "\n  No bugs found.\n"))
+}
+
+// TestSummary_PerFile verifies each file is analyzed on its own and gets a section.
+func TestSummary_PerFile(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+
+	fake := &fakeLLM{generate: func(_ context.Context, prompt string) (string, error) {
+		if strings.Contains(prompt, "files: a.go.") {
+			return "About a.", nil
+		}
+
+		return "About b.", nil
+	}}
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+		CurrentDir: dir, Prompt: "explain", AbsFilePaths: paths,
+		EditFormat: cmd.EditFormatSearchReplace, Summary: true, PerFile: true, OutputFile: "out.md",
+	})
+	assert.NilError(t, err)
+
+	assert.Assert(t, cmp.Len(fake.prompts, 2))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "out.md")), "## a.go\n\nAbout a.\n\n## b.go\n\nAbout b.\n"))
+}
+
+// TestSummary_RefusesSourceOutput verifies the output can't overwrite a file or context
+// file being analyzed, even through a symlink.
+func TestSummary_RefusesSourceOutput(t *testing.T) {
+	dir, paths := setupFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	assert.NilError(t, os.Symlink(paths[0], filepath.Join(dir, "link.md")))
+
+	fake := &fakeLLM{generate: func(_ context.Context, _ string) (string, error) {
+		return "No bugs found.", nil
+	}}
+
+	for _, output := range []string{"a.go", paths[0], "link.md", "b.go"} {
+		err := cmd.ExecuteCodeCommand(context.Background(), fake, &cmd.CodeRequest{
+			CurrentDir: dir, Prompt: "review", AbsFilePaths: paths[:1], ContextFiles: paths[1:],
+			EditFormat: cmd.EditFormatSearchReplace, Summary: true, OutputFile: output,
+		})
+		assert.Assert(t, errors.Is(err, cmd.ErrSummaryOutputIsSource), "%s: got %v", output, err)
+	}
+
+	assert.Assert(t, cmp.Len(fake.prompts, 0), "The llm should not be called.")
+	assert.Assert(t, cmp.Equal(readFile(t, paths[0]), "package a\n"))
+	assert.Assert(t, cmp.Equal(readFile(t, paths[1]), "package b\n"))
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/chunk"
+	"github.com/chrisrob11/codeassistant/internal/edit"
+	"github.com/teilomillet/gollm"
+)
+
+// Symbol errors.
+var (
+	ErrSymbolAmbiguous = errors.New("symbol is declared in several files")
+)
+
+// symbolTarget is a declaration to edit and the file that holds it.
+type symbolTarget struct {
+	Symbol string
+	File   string
+}
+
+// resolveSymbols finds the Go file declaring each symbol among the given files and
+// returns the targets along with the files that hold them.
+func resolveSymbols(symbols, files []string) ([]symbolTarget, []string, error) {
+	if len(symbols) == 0 {
+		return nil, files, nil
+	}
+
+	contents := map[string]string{}
+
+	for _, file := range files {
+		if filepath.Ext(file) != ".go" {
+			continue
+		}
+
+		// nolint:gosec //Why: files are validated within a specific path
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %v", ErrFailedToReadFile, file, err)
+		}
+
+		contents[file] = string(content)
+	}
+
+	targets := make([]symbolTarget, 0, len(symbols))
+	targetFiles := []string{}
+
+	for _, symbol := range symbols {
+		matches := []string{}
+
+		for _, file := range files {
+			content, ok := contents[file]
+			if !ok {
+				continue
+			}
+
+			if _, _, err := chunk.Isolate(file, content, symbol); err == nil {
+				matches = append(matches, file)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return nil, nil, fmt.Errorf("%w: %s", chunk.ErrSymbolNotFound, symbol)
+		case 1:
+		default:
+			return nil, nil, fmt.Errorf("%w: %s in %s (use --files to pick one)",
+				ErrSymbolAmbiguous, symbol, strings.Join(matches, ", "))
+		}
+
+		targets = append(targets, symbolTarget{Symbol: symbol, File: matches[0]})
+
+		if !slices.Contains(targetFiles, matches[0]) {
+			targetFiles = append(targetFiles, matches[0])
+		}
+	}
+
+	return targets, targetFiles, nil
+}
+
+// modifySymbols edits each targeted declaration on its own and splices the results
+// back into their files, leaving the rest of each file untouched.
+func modifySymbols(
+	ctx context.Context, llm gollm.LLM, req *codeRequest, refs *promptReferences,
+) (map[string]fileChange, error) {
+	modifications := make(map[string]fileChange, len(req.AbsFilePaths))
+
+	for _, file := range req.AbsFilePaths {
+		relPath, err := filepath.Rel(req.CurrentDir, file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToResolveAbsPath, err)
+		}
+
+		content, err := req.readFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrFailedToReadFile, relPath, err)
+		}
+
+		modified := string(content)
+
+		for _, target := range req.Symbols {
+			if target.File != file {
+				continue
+			}
+
+			modified, err = modifySymbol(ctx, llm, req, filepath.ToSlash(relPath), modified, target.Symbol, refs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		modifications[file] = fileChange{Content: modified}
+	}
+
+	return modifications, nil
+}
+
+// modifySymbol asks the llm to change one declaration and returns the file with it replaced.
+func modifySymbol(
+	ctx context.Context, llm gollm.LLM, req *codeRequest, relPath, content, symbol string, refs *promptReferences,
+) (string, error) {
+	chunks, index, err := chunk.Isolate(relPath, content, symbol)
+	if err != nil {
+		return "", err
+	}
+
+	decl := chunks[index].Content
+	label := fmt.Sprintf("%s %s", relPath, symbol)
+	decls := chunk.Declarations(relPath, content)
+	fullPrompt := buildSymbolPrompt(req.Prompt, relPath, symbol, req.EditFormat, decls, decl, refs)
+
+	expectedOutput := expectedOutputTokens(req.EditFormat, req.Budget.count(decl))
+	if err := req.Budget.check(label, fullPrompt, expectedOutput); err != nil {
+		return "", err
+	}
+
+	response, err := processWithLLM(ctx, llm, fullPrompt)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", label, err)
+	}
+
+	edited, err := applySymbolResponse(req.EditFormat, decl, response)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrEditNotApplied, label, err)
+	}
+
+	chunks[index].Content = edited
+	modified := chunk.Join(chunks)
+
+	// The declaration alone isn't a Go file, so the spliced file is checked instead
+	if _, err := parser.ParseFile(token.NewFileSet(), relPath, modified, parser.AllErrors); err != nil {
+		return "", fmt.Errorf("%w: %s: %w: %v", ErrEditNotApplied, label, edit.ErrNotCode, err)
+	}
+
+	return modified, nil
+}
+
+// applySymbolResponse turns the llm response into the new declaration, keeping it on lines of its own.
+func applySymbolResponse(format, decl, response string) (string, error) {
+	var (
+		edited string
+		err    error
+	)
+
+	if format == EditFormatWhole {
+		// An empty path skips the Go parse check that only whole files pass
+		edited, err = edit.ExtractCode(response, "")
+	} else {
+		edited, err = applyResponse(EditFormatSearchReplace, "", decl, response)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasSuffix(decl, "\n") && !strings.HasSuffix(edited, "\n") {
+		edited += "\n"
+	}
+
+	return edited, nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"github.com/chrisrob11/codeassistant/internal/verify"
+	"github.com/teilomillet/gollm"
+)
+
+// Verification errors.
+var (
+	ErrVerificationFailed = errors.New("verification failed")
+	ErrEmptyCheckCommand  = errors.New("check command is empty")
+)
+
+// Check output beyond this many bytes is trimmed from the front, keeping the
+// end where compilers and test runners summarize the failure.
+const maxCheckOutput = 8000
+
+// verifyStep runs the checks against the files written by the step and records
+// the outcome on it. With FixUntilGreen failures are sent back to the llm as
+// sub-steps, and a step that still fails is rolled back when requested.
+func verifyStep(ctx context.Context, llm gollm.LLM, currentSession *session.Session,
+	step *session.Step, req *codeRequest) error {
+	results, err := runChecks(ctx, req, step)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	if err := recordVerification(req.CurrentDir, currentSession, step, step, results); err != nil {
+		return err
+	}
+
+	results, err = fixUntilGreen(ctx, llm, currentSession, step, req, results)
+	if err != nil {
+		return err
+	}
+
+	if verify.Passed(results) {
+		return nil
+	}
+
+	if !req.RollbackOnFailure {
+		return fmt.Errorf("%w: step %d kept, see ca review --step %d", ErrVerificationFailed, step.ID, step.ID)
+	}
+
+	if err := executeRollbackCommand(req.CurrentDir, &rollbackRequest{StepID: step.ID}); err != nil {
+		return errors.Join(fmt.Errorf("%w: step %d", ErrVerificationFailed, step.ID), err)
+	}
+
+	return fmt.Errorf("%w: step %d rolled back", ErrVerificationFailed, step.ID)
+}
+
+// fixUntilGreen sends failed checks back to the llm as sub-steps until they pass or the
+// attempts run out, and returns the last results. The post git state of the step is
+// captured again after each attempt so it reflects the fixes.
+func fixUntilGreen(ctx context.Context, llm gollm.LLM, currentSession *session.Session,
+	step *session.Step, req *codeRequest, results []verify.Result) ([]verify.Result, error) {
+	for attempt := 1; req.FixUntilGreen && !verify.Passed(results) && attempt <= req.MaxFixAttempts; attempt++ {
+		fmt.Printf("🔧 Fix attempt %d of %d\n", attempt, req.MaxFixAttempts)
+
+		subStep, err := fixStep(ctx, llm, step, req, results)
+		if err != nil {
+			return nil, err
+		}
+
+		step.SubSteps = append(step.SubSteps, subStep)
+
+		step.Git.Post, err = captureGitState(req.CurrentDir)
+		if err != nil {
+			return nil, err
+		}
+
+		results, err = runChecks(ctx, req, step)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := recordVerification(req.CurrentDir, currentSession, step, subStep, results); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// runChecks runs the Go checks when verifying and the check command when fixing.
+func runChecks(ctx context.Context, req *codeRequest, step *session.Step) ([]verify.Result, error) {
+	results := []verify.Result{}
+
+	if req.Verify {
+		results = verify.GoChecks(ctx, req.CurrentDir, stepFiles(req.CurrentDir, step))
+		if !verify.Passed(results) {
+			return results, nil
+		}
+	}
+
+	if req.FixUntilGreen {
+		if strings.TrimSpace(req.CheckCommand) == "" {
+			return nil, ErrEmptyCheckCommand
+		}
+
+		// Run by the shell so quotes, pipes and && work as they do on the command line
+		results = append(results, verify.RunCommand(ctx, req.CurrentDir, req.CheckCommand, "sh", "-c", req.CheckCommand))
+	}
+
+	return results, nil
+}
+
+// recordVerification stores the results on the attempt that produced them, updates the
+// overall outcome of the step and saves the session.
+func recordVerification(currentDir string, currentSession *session.Session,
+	step, attempt *session.Step, results []verify.Result) error {
+	attempt.Verification = newVerification(results)
+	step.Verification = attempt.Verification
+
+	printVerification(attempt.Verification)
+
+	if err := session.SaveCurrentSession(currentDir, currentSession); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToSaveSession, err)
+	}
+
+	return nil
+}
+
+// fixStep asks the llm to fix the files of the step using the failed check output,
+// writes the result and returns it as a sub-step.
+func fixStep(
+	ctx context.Context, llm gollm.LLM, step *session.Step, req *codeRequest, results []verify.Result,
+) (*session.Step, error) {
+	files := stepFiles(req.CurrentDir, step)
+
+	fixReq := *req
+	fixReq.Prompt = buildFixPrompt(req.Prompt, failedCheckOutput(results))
+	fixReq.AbsFilePaths = files
+	fixReq.Symbols = nil // Failures may be anywhere in the files
+
+	modifications, err := modifyCode(ctx, llm, &fixReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAIProcessingFailed, err)
+	}
+
+	snapshots, err := writeModifications(req.CurrentDir, modifications)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session.Step{
+		ID:        len(step.SubSteps) + 1,
+		Type:      session.StepTypeFix,
+		Command:   newCommand(&fixReq),
+		Timestamp: time.Now(),
+		FilesDiff: session.NewFilesDiff(snapshots),
+	}, nil
+}
+
+// stepFiles returns the absolute paths of the files that exist after the step.
+func stepFiles(currentDir string, step *session.Step) []string {
+	snapshots := step.CombinedSnapshots()
+	files := make([]string, 0, len(snapshots))
+
+	for _, snapshot := range snapshots {
+		if snapshot.PostHash != "" {
+			files = append(files, filepath.Join(currentDir, snapshot.Path))
+		}
+	}
+
+	return files
+}
+
+// failedCheckOutput joins the output of the failed checks for the llm.
+func failedCheckOutput(results []verify.Result) string {
+	var b strings.Builder
+
+	for _, result := range results {
+		if !result.Passed {
+			fmt.Fprintf(&b, "$ %s\n%s\n", result.Name, result.Output)
+		}
+	}
+
+	return tailOutput(b.String())
+}
+
+// tailOutput trims long output down to its end.
+func tailOutput(output string) string {
+	if len(output) > maxCheckOutput {
+		return "...\n" + output[len(output)-maxCheckOutput:]
+	}
+
+	return output
+}
+
+// newVerification converts check results into their session record.
+func newVerification(results []verify.Result) *session.Verification {
+	verification := &session.Verification{
+		Status: session.VerificationPassed,
+		Checks: make([]session.CheckResult, 0, len(results)),
+	}
+
+	if !verify.Passed(results) {
+		verification.Status = session.VerificationFailed
+	}
+
+	for _, result := range results {
+		result.Output = tailOutput(result.Output)
+		verification.Checks = append(verification.Checks, session.CheckResult(result))
+	}
+
+	return verification
+}
+
+// printVerification reports the outcome of each check.
+func printVerification(verification *session.Verification) {
+	for _, check := range verification.Checks {
+		if check.Passed {
+			fmt.Printf("✅ %s passed\n", check.Name)
+			continue
+		}
+
+		fmt.Printf("❌ %s failed:\n%s\n", check.Name, check.Output)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/cmd"
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+)
+
+// fixRequest edits main.go and checks the result with command until it passes.
+func fixRequest(dir, command string) *cmd.CodeRequest {
+	req := editRequest(dir, "main.go")
+	req.EditFormat, req.PerFile = cmd.EditFormatSearchReplace, false
+	req.FixUntilGreen, req.CheckCommand, req.MaxFixAttempts = true, command, 1
+
+	return req
+}
+
+// TestVerify_CheckCommandQuoting verifies the check command keeps quoted arguments whole.
+func TestVerify_CheckCommandQuoting(t *testing.T) {
+	dir := setupRepoSession(t)
+
+	err := cmd.ExecuteCodeCommand(context.Background(), commitLLM(""), fixRequest(dir, `test "a b" = 'a b'`))
+	assert.NilError(t, err)
+
+	currentSession, err := session.LoadCurrentSession(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, currentSession.Steps[0].Verification.Status, session.VerificationPassed)
+	assert.Equal(t, len(currentSession.Steps[0].SubSteps), 0)
+}
+
+// TestVerify_FixRefreshesGitState verifies the post git state of a step includes the
+// files written by its fix attempts.
+func TestVerify_FixRefreshesGitState(t *testing.T) {
+	dir := setupRepoSession(t)
+
+	fake := &fakeLLM{generate: func(_ context.Context, prompt string) (string, error) {
+		if strings.Contains(prompt, "helper.go") {
+			return "FILE: helper.go\n<<<<<<< SEARCH\n=======\npackage main\n>>>>>>> REPLACE\n", nil
+		}
+
+		return "FILE: main.go\n<<<<<<< SEARCH\npackage main\n=======\npackage main\n\nfunc main() {}\n>>>>>>> REPLACE\n", nil
+	}}
+
+	err := cmd.ExecuteCodeCommand(context.Background(), fake, fixRequest(dir, "test -f helper.go"))
+	assert.NilError(t, err)
+
+	currentSession, err := session.LoadCurrentSession(dir)
+	assert.NilError(t, err)
+
+	step := currentSession.Steps[0]
+	assert.Equal(t, len(step.SubSteps), 1)
+	assert.Equal(t, step.Verification.Status, session.VerificationPassed)
+	assert.DeepEqual(t, step.Git.Post.UncommittedFiles, []string{"main.go", "helper.go"})
+}
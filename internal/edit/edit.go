@@ -0,0 +1,233 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+// Package edit parses and applies the edit formats returned by an llm.
+package edit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Edit errors.
+var (
+	ErrNoEditBlocks    = errors.New("response contains no edit blocks")
+	ErrMalformedBlock  = errors.New("malformed edit block")
+	ErrSearchNotFound  = errors.New("search text not found in file")
+	ErrAmbiguousSearch = errors.New("search text matches more than once")
+)
+
+// Markers delimiting a search/replace block.
+var (
+	searchMarker  = regexp.MustCompile(`^<{5,9} ?SEARCH\s*$`)
+	dividerMarker = regexp.MustCompile(`^={5,9}\s*$`)
+	replaceMarker = regexp.MustCompile(`^>{5,9} ?REPLACE\s*$`)
+)
+
+// Block is a single search/replace edit. Search must match the original
+// content exactly once; an empty Search is only valid for an empty file.
+type Block struct {
+	Search  string
+	Replace string
+}
+
+// blockState tracks which part of a block the parser is in.
+type blockState int
+
+const (
+	stateOutside blockState = iota
+	stateSearch
+	stateReplace
+)
+
+// ParseBlocks extracts the search/replace blocks from a response. Text
+// outside of the blocks, such as explanations or code fences, is ignored.
+func ParseBlocks(response string) ([]Block, error) {
+	blocks := []Block{}
+	state := stateOutside
+	lineNum := 0
+
+	var search, replace strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(response)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+
+		switch state {
+		case stateOutside:
+			if searchMarker.MatchString(line) {
+				search.Reset()
+				replace.Reset()
+
+				state = stateSearch
+			}
+		case stateSearch:
+			if dividerMarker.MatchString(line) {
+				state = stateReplace
+				continue
+			}
+
+			if searchMarker.MatchString(line) || replaceMarker.MatchString(line) {
+				return nil, fmt.Errorf("%w: unexpected marker on line %d", ErrMalformedBlock, lineNum)
+			}
+
+			search.WriteString(line + "\n")
+		case stateReplace:
+			if replaceMarker.MatchString(line) {
+				blocks = append(blocks, Block{Search: search.String(), Replace: replace.String()})
+				state = stateOutside
+
+				continue
+			}
+
+			if searchMarker.MatchString(line) {
+				return nil, fmt.Errorf("%w: unexpected marker on line %d", ErrMalformedBlock, lineNum)
+			}
+
+			replace.WriteString(line + "\n")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedBlock, err)
+	}
+
+	if state != stateOutside {
+		return nil, fmt.Errorf("%w: unterminated block", ErrMalformedBlock)
+	}
+
+	if len(blocks) == 0 {
+		return nil, ErrNoEditBlocks
+	}
+
+	return blocks, nil
+}
+
+// Apply applies the blocks in order to the original content. It fails without
+// a partial result if any block does not match exactly once.
+func Apply(original string, blocks []Block) (string, error) {
+	// Blocks are parsed line by line, so compare against content that ends in a newline
+	missingNewline := original != "" && !strings.HasSuffix(original, "\n")
+	content := original
+
+	if missingNewline {
+		content += "\n"
+	}
+
+	for i, block := range blocks {
+		updated, err := applyBlock(content, block)
+		if err != nil {
+			return "", fmt.Errorf("block %d: %w", i+1, err)
+		}
+
+		content = updated
+	}
+
+	if missingNewline {
+		content = strings.TrimSuffix(content, "\n")
+	}
+
+	return content, nil
+}
+
+// applyBlock applies a single block to the content.
+func applyBlock(content string, block Block) (string, error) {
+	if block.Search == "" {
+		if content != "" {
+			return "", fmt.Errorf("%w: empty search on a non-empty file", ErrMalformedBlock)
+		}
+
+		return block.Replace, nil
+	}
+
+	matches := lineMatches(content, block.Search)
+
+	switch len(matches) {
+	case 1:
+		return content[:matches[0]] + block.Replace + content[matches[0]+len(block.Search):], nil
+	case 0:
+		return applyLooseBlock(content, block)
+	default:
+		return "", fmt.Errorf("%w: %s", ErrAmbiguousSearch, firstLine(block.Search))
+	}
+}
+
+// lineMatches returns the offsets where search matches whole lines of the content, so a
+// search for "foo()" never edits the middle of "bar.foo()" or the tail of an indented line.
+func lineMatches(content, search string) []int {
+	matches := []int{}
+
+	for offset := 0; ; {
+		i := strings.Index(content[offset:], search)
+		if i < 0 {
+			return matches
+		}
+
+		start, end := offset+i, offset+i+len(search)
+		startsLine := start == 0 || content[start-1] == '\n'
+		endsLine := strings.HasSuffix(search, "\n") || end == len(content) || content[end] == '\n'
+
+		if startsLine && endsLine {
+			matches = append(matches, start)
+		}
+
+		offset = start + 1
+	}
+}
+
+// applyLooseBlock retries a block ignoring trailing whitespace on each line,
+// which models frequently drop or add.
+func applyLooseBlock(content string, block Block) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	searchLines := strings.SplitAfter(strings.TrimSuffix(block.Search, "\n"), "\n")
+
+	match := -1
+
+	for start := 0; start+len(searchLines) <= len(lines); start++ {
+		if !linesMatch(lines[start:start+len(searchLines)], searchLines) {
+			continue
+		}
+
+		if match >= 0 {
+			return "", fmt.Errorf("%w: %s", ErrAmbiguousSearch, firstLine(block.Search))
+		}
+
+		match = start
+	}
+
+	if match < 0 {
+		return "", fmt.Errorf("%w: %s", ErrSearchNotFound, firstLine(block.Search))
+	}
+
+	var b strings.Builder
+
+	b.WriteString(strings.Join(lines[:match], ""))
+	b.WriteString(block.Replace)
+	b.WriteString(strings.Join(lines[match+len(searchLines):], ""))
+
+	return b.String(), nil
+}
+
+// linesMatch compares lines ignoring trailing whitespace.
+func linesMatch(lines, searchLines []string) bool {
+	for i := range searchLines {
+		if strings.TrimRight(lines[i], " \t\r\n") != strings.TrimRight(searchLines[i], " \t\r\n") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// firstLine returns the first line of text for use in error messages.
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return strings.TrimSpace(line)
+}
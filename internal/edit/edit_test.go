@@ -0,0 +1,139 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package edit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/edit"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+const original = `package main
+
+func main() {
+	fmt.Println("hello")
+}
+`
+
+// TestParseBlocks_IgnoresSurroundingText verifies blocks are found inside prose and fences.
+func TestParseBlocks_IgnoresSurroundingText(t *testing.T) {
+	response := "Here is the change:\n```go\n" +
+		"<<<<<<< SEARCH\n\tfmt.Println(\"hello\")\n=======\n\tfmt.Println(\"world\")\n>>>>>>> REPLACE\n" +
+		"```\n"
+
+	blocks, err := edit.ParseBlocks(response)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, blocks, []edit.Block{
+		{Search: "\tfmt.Println(\"hello\")\n", Replace: "\tfmt.Println(\"world\")\n"},
+	})
+}
+
+// TestParseBlocks_Errors verifies malformed and missing blocks are rejected.
+func TestParseBlocks_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		err      error
+	}{
+		{name: "no blocks", response: "package main\n", err: edit.ErrNoEditBlocks},
+		{name: "unterminated", response: "<<<<<<< SEARCH\na\n=======\nb\n", err: edit.ErrMalformedBlock},
+		{name: "nested", response: "<<<<<<< SEARCH\n<<<<<<< SEARCH\n", err: edit.ErrMalformedBlock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := edit.ParseBlocks(tt.response)
+			assert.Assert(t, errors.Is(err, tt.err), "Expected %v, got: %v", tt.err, err)
+		})
+	}
+}
+
+// TestApply covers exact, whitespace tolerant and failing matches.
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		blocks   []edit.Block
+		want     string
+		err      error
+	}{
+		{
+			name:     "exact",
+			original: original,
+			blocks:   []edit.Block{{Search: "\tfmt.Println(\"hello\")\n", Replace: "\tfmt.Println(\"world\")\n"}},
+			want:     "package main\n\nfunc main() {\n\tfmt.Println(\"world\")\n}\n",
+		},
+		{
+			name:     "trailing whitespace",
+			original: original,
+			blocks:   []edit.Block{{Search: "func main() {  \n", Replace: "func run() {\n"}},
+			want:     "package main\n\nfunc run() {\n\tfmt.Println(\"hello\")\n}\n",
+		},
+		{
+			name:     "no trailing newline",
+			original: "a\nb",
+			blocks:   []edit.Block{{Search: "b\n", Replace: "c\n"}},
+			want:     "a\nc",
+		},
+		{
+			name:     "empty file",
+			original: "",
+			blocks:   []edit.Block{{Search: "", Replace: "package main\n"}},
+			want:     "package main\n",
+		},
+		{
+			name:     "not found",
+			original: original,
+			blocks:   []edit.Block{{Search: "func other() {\n", Replace: ""}},
+			err:      edit.ErrSearchNotFound,
+		},
+		{
+			name:     "ambiguous",
+			original: "a\na\n",
+			blocks:   []edit.Block{{Search: "a\n", Replace: "b\n"}},
+			err:      edit.ErrAmbiguousSearch,
+		},
+		{
+			name:     "mid-line match",
+			original: "func f() {\n\tbar.foo()\n}\n",
+			blocks:   []edit.Block{{Search: "foo()\n", Replace: "baz()\n"}},
+			err:      edit.ErrSearchNotFound,
+		},
+		{
+			name:     "unindented search of indented line",
+			original: original,
+			blocks:   []edit.Block{{Search: "fmt.Println(\"hello\")\n", Replace: "fmt.Println(\"world\")\n"}},
+			err:      edit.ErrSearchNotFound,
+		},
+		{
+			name:     "whole line among partial matches",
+			original: "\tbar.foo()\nfoo()\n",
+			blocks:   []edit.Block{{Search: "foo()\n", Replace: "baz()\n"}},
+			want:     "\tbar.foo()\nbaz()\n",
+		},
+		{
+			name:     "empty search on existing file",
+			original: original,
+			blocks:   []edit.Block{{Search: "", Replace: "x\n"}},
+			err:      edit.ErrMalformedBlock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := edit.Apply(tt.original, tt.blocks)
+			if tt.err != nil {
+				assert.Assert(t, errors.Is(err, tt.err), "Expected %v, got: %v", tt.err, err)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Assert(t, cmp.Equal(got, tt.want))
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package edit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Multi-file errors.
+var (
+	ErrMissingFilePath   = errors.New("edit is not preceded by a file path")
+	ErrUnterminatedFile  = errors.New("file section is not terminated")
+	ErrNoFileSections    = errors.New("response contains no file sections")
+	ErrDuplicateFilePath = errors.New("file appears more than once")
+)
+
+// Delimiters used to mark files in multi-file prompts and responses.
+const (
+	FileStartFormat = "=== FILE: %s ==="
+	FileEnd         = "=== END FILE ==="
+)
+
+var (
+	// A file header in a search/replace response, e.g. "FILE: main.go" or "### FILE: `main.go`".
+	fileHeader = regexp.MustCompile("^\\s*(?:#+\\s*)?(?:=== )?FILE:\\s*`?([^`\\s]+?)`?(?: ===)?\\s*$")
+	// A request to delete a file, e.g. "DELETE: old.go" or "=== DELETE: old.go ===".
+	deleteHeader = regexp.MustCompile("^\\s*(?:#+\\s*)?(?:=== )?DELETE:\\s*`?([^`\\s]+?)`?(?: ===)?\\s*$")
+	fileStart    = regexp.MustCompile(`^=== FILE: (.+?) ===\s*$`)
+	fileEnd      = regexp.MustCompile(`^=== END FILE ===\s*$`)
+)
+
+// ParseFileBlocks extracts search/replace blocks grouped by the file path
+// that precedes them. Paths are cleaned and use forward slashes.
+func ParseFileBlocks(response string) (map[string][]Block, error) {
+	files := map[string][]Block{}
+	current := ""
+
+	var section strings.Builder
+
+	flush := func() error {
+		if strings.TrimSpace(section.String()) == "" {
+			return nil
+		}
+
+		blocks, err := ParseBlocks(section.String())
+		if errors.Is(err, ErrNoEditBlocks) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if current == "" {
+			return ErrMissingFilePath
+		}
+
+		files[current] = append(files[current], blocks...)
+
+		return nil
+	}
+
+	inBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(response)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case searchMarker.MatchString(line):
+			inBlock = true
+		case replaceMarker.MatchString(line):
+			inBlock = false
+		case !inBlock:
+			if match := fileHeader.FindStringSubmatch(line); match != nil {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+
+				section.Reset()
+
+				current = cleanPath(match[1])
+
+				continue
+			}
+		}
+
+		section.WriteString(line + "\n")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedBlock, err)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoEditBlocks
+	}
+
+	return files, nil
+}
+
+// ParseFileSections extracts whole-file contents delimited by FileStartFormat
+// and FileEnd lines. Text between sections is ignored.
+func ParseFileSections(response string) (map[string]string, error) {
+	files := map[string]string{}
+	lines := strings.SplitAfter(response, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		match := fileStart.FindStringSubmatch(strings.TrimRight(lines[i], "\r\n"))
+		if match == nil {
+			continue
+		}
+
+		filePath := cleanPath(match[1])
+		if _, ok := files[filePath]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateFilePath, filePath)
+		}
+
+		var content strings.Builder
+
+		j := i + 1
+		for ; j < len(lines) && !fileEnd.MatchString(strings.TrimRight(lines[j], "\r\n")); j++ {
+			content.WriteString(lines[j])
+		}
+
+		if j == len(lines) {
+			return nil, fmt.Errorf("%w: %s", ErrUnterminatedFile, filePath)
+		}
+
+		files[filePath] = content.String()
+		i = j
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoFileSections
+	}
+
+	return files, nil
+}
+
+// ParseDeletes returns the files a response asks to delete. Lines inside
+// search/replace blocks and whole-file sections are file content, not requests.
+func ParseDeletes(response string) []string {
+	deletes := []string{}
+	inBlock, inFile := false, false
+
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		switch {
+		case inFile:
+			inFile = !fileEnd.MatchString(line)
+		case inBlock:
+			inBlock = !replaceMarker.MatchString(line)
+		case searchMarker.MatchString(line):
+			inBlock = true
+		case fileStart.MatchString(line):
+			inFile = true
+		default:
+			if match := deleteHeader.FindStringSubmatch(line); match != nil {
+				deletes = append(deletes, cleanPath(match[1]))
+			}
+		}
+	}
+
+	return deletes
+}
+
+// cleanPath normalizes a path written by the llm.
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean(strings.ReplaceAll(p, "\\", "/")), "./")
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package edit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/edit"
+	"gotest.tools/v3/assert"
+)
+
+// TestParseFileBlocks verifies blocks are grouped under the file header preceding them.
+func TestParseFileBlocks(t *testing.T) {
+	response := "Renaming the type in both files.\n\n" +
+		"FILE: ./types.go\n" +
+		"<<<<<<< SEARCH\ntype Old struct{}\n=======\ntype New struct{}\n>>>>>>> REPLACE\n\n" +
+		"### FILE: `cmd/main.go`\n```go\n" +
+		"<<<<<<< SEARCH\nvar x Old\n=======\nvar x New\n>>>>>>> REPLACE\n" +
+		"<<<<<<< SEARCH\nFILE: not a header\n=======\n>>>>>>> REPLACE\n```\n"
+
+	files, err := edit.ParseFileBlocks(response)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, map[string][]edit.Block{
+		"types.go": {{Search: "type Old struct{}\n", Replace: "type New struct{}\n"}},
+		"cmd/main.go": {
+			{Search: "var x Old\n", Replace: "var x New\n"},
+			{Search: "FILE: not a header\n", Replace: ""},
+		},
+	})
+}
+
+// TestParseFileBlocks_MissingPath ensures blocks without a file header are rejected.
+func TestParseFileBlocks_MissingPath(t *testing.T) {
+	_, err := edit.ParseFileBlocks("<<<<<<< SEARCH\na\n=======\nb\n>>>>>>> REPLACE\n")
+	assert.Assert(t, errors.Is(err, edit.ErrMissingFilePath), "Expected ErrMissingFilePath, got: %v", err)
+}
+
+// TestParseFileSections verifies whole-file sections are split by their delimiters.
+func TestParseFileSections(t *testing.T) {
+	response := "=== FILE: a.go ===\npackage a\n=== END FILE ===\nsome chatter\n" +
+		"=== FILE: b/b.go ===\npackage b\n\nvar B = 1\n=== END FILE ===\n"
+
+	files, err := edit.ParseFileSections(response)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, map[string]string{
+		"a.go":   "package a\n",
+		"b/b.go": "package b\n\nvar B = 1\n",
+	})
+
+	_, err = edit.ParseFileSections("=== FILE: a.go ===\npackage a\n")
+	assert.Assert(t, errors.Is(err, edit.ErrUnterminatedFile), "Expected ErrUnterminatedFile, got: %v", err)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Sanitize errors.
+var (
+	ErrEmptyResponse = errors.New("response contains no code")
+	ErrNotCode       = errors.New("response looks like an explanation instead of code")
+)
+
+// Share of non-blank lines that must read like sentences before a payload is treated as prose.
+const proseLineRatio = 0.6
+
+var (
+	fenceLine = regexp.MustCompile("^\\s*(```|~~~)")
+	// Chatter models put around code, e.g. "Here is the updated code:".
+	chatterLine = regexp.MustCompile(
+		`(?i)^(here('s| is| are)|sure|certainly|of course|okay|ok|below|the (updated|modified|complete)|` +
+			`i('ve| have)|this (code|change|version)|these changes|note:)\b`)
+	// Characters that rarely appear in prose but are common in code.
+	codeChars = regexp.MustCompile(`[{}();=<>\[\]#]|:=|//|/\*`)
+)
+
+// ExtractCode pulls the code payload for a file out of a whole-file response.
+// Markdown fences and surrounding chatter are removed, and a response that
+// is an explanation rather than code is rejected.
+func ExtractCode(response, path string) (string, error) {
+	payload, fenced := extractFenced(response)
+	if !fenced {
+		payload = stripChatter(response)
+	}
+
+	if strings.TrimSpace(payload) == "" {
+		return "", ErrEmptyResponse
+	}
+
+	if err := checkIsCode(payload, path); err != nil {
+		return "", err
+	}
+
+	return payload, nil
+}
+
+// extractFenced returns the contents of a fence wrapping the whole payload, which is
+// the response less the chatter before and after it. Fences inside the payload, such as
+// examples in a markdown file or lines of a Go raw string, are content and are kept. An
+// opening fence that is never closed, as happens with truncated responses, runs to the end.
+func extractFenced(response string) (string, bool) {
+	lines := strings.SplitAfter(response, "\n")
+	start, end := trimChatter(lines)
+
+	if start == end || !fenceLine.MatchString(lines[start]) {
+		return "", false
+	}
+
+	inner := lines[start+1 : end]
+	if len(inner) > 0 && fenceLine.MatchString(inner[len(inner)-1]) {
+		return strings.Join(inner[:len(inner)-1], ""), true
+	}
+
+	for _, line := range inner {
+		if fenceLine.MatchString(line) {
+			return "", false
+		}
+	}
+
+	return strings.Join(inner, ""), true
+}
+
+// stripChatter removes prose lines before and after the code in an unfenced response.
+func stripChatter(response string) string {
+	lines := strings.SplitAfter(response, "\n")
+	start, end := trimChatter(lines)
+
+	return strings.Join(lines[start:end], "")
+}
+
+// trimChatter returns the range of lines left after dropping chatter at both ends.
+func trimChatter(lines []string) (start, end int) {
+	start, end = 0, len(lines)
+
+	for start < end && isChatter(lines[start]) {
+		start++
+	}
+
+	for end > start && isChatter(lines[end-1]) {
+		end--
+	}
+
+	return start, end
+}
+
+// isChatter reports whether a line is blank or conversational filler.
+func isChatter(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || (chatterLine.MatchString(trimmed) && !codeChars.MatchString(trimmed))
+}
+
+// checkIsCode rejects payloads that are not code. Go sources must parse;
+// other non-documentation files are rejected when most lines read like sentences.
+func checkIsCode(payload, path string) error {
+	switch filepath.Ext(path) {
+	case ".go":
+		if _, err := parser.ParseFile(token.NewFileSet(), path, payload, parser.AllErrors); err != nil {
+			return fmt.Errorf("%w: %v", ErrNotCode, err)
+		}
+
+		return nil
+	case ".md", ".txt", ".rst":
+		// Prose is the expected content of documentation files
+		return nil
+	}
+
+	total, prose := 0, 0
+
+	for _, line := range strings.Split(payload, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		total++
+
+		if isProseLine(trimmed) {
+			prose++
+		}
+	}
+
+	if total > 0 && float64(prose)/float64(total) >= proseLineRatio {
+		return ErrNotCode
+	}
+
+	return nil
+}
+
+// isProseLine reports whether a line reads like a sentence rather than code.
+func isProseLine(line string) bool {
+	if codeChars.MatchString(line) {
+		return false
+	}
+
+	words := strings.Fields(line)
+	if len(words) < 4 {
+		return false
+	}
+
+	first := words[0][0]
+	last := line[len(line)-1]
+
+	return (first >= 'A' && first <= 'Z' || first == '-' || first == '*') &&
+		(last == '.' || last == ':' || last == '!' || last == '?' || last == ',')
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package edit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/edit"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// TestExtractCode covers fenced, unfenced and rejected responses.
+func TestExtractCode(t *testing.T) {
+	const code = "package main\n\nfunc main() {}\n"
+
+	const readme = "# Example\n\nRun it:\n\n```bash\ngo run .\n```\n\nThe entry point:\n\n" +
+		"```go\nfunc main() {}\n```\n\nThat is all.\n"
+
+	const rawString = "package main\n\nconst usage = `\n~~~bash\nca code\n~~~\n`\n"
+
+	tests := []struct {
+		name     string
+		path     string
+		response string
+		want     string
+		err      error
+	}{
+		{name: "plain code", path: "main.go", response: code, want: code},
+		{
+			name:     "fenced with chatter",
+			path:     "main.go",
+			response: "Here is your updated code:\n\n```go\n" + code + "```\n\nThis version removes the loop.\n",
+			want:     code,
+		},
+		{
+			name:     "unfenced with chatter",
+			path:     "main.go",
+			response: "Sure! Here is the updated file:\n" + code + "\nI have removed the loop.\n",
+			want:     code,
+		},
+		{name: "truncated fence", path: "main.go", response: "```go\n" + code, want: code},
+		{
+			name:     "explanation instead of go",
+			path:     "main.go",
+			response: "The function already handles errors correctly, so no change is needed.\n",
+			err:      edit.ErrNotCode,
+		},
+		{
+			name: "explanation instead of yaml",
+			path: "config.yaml",
+			response: "I cannot make that change because the file is generated.\n" +
+				"You should update the template instead and run the generator again.\n",
+			err: edit.ErrNotCode,
+		},
+		{
+			name:     "documentation keeps prose",
+			path:     "README.md",
+			response: "This project keeps documentation in sentences.\n",
+			want:     "This project keeps documentation in sentences.\n",
+		},
+		{
+			name:     "markdown with fenced examples",
+			path:     "README.md",
+			response: readme,
+			want:     readme,
+		},
+		{
+			name:     "fenced markdown with fenced examples",
+			path:     "README.md",
+			response: "Here is the updated file:\n\n````markdown\n" + readme + "````\n",
+			want:     readme,
+		},
+		{
+			name:     "go raw string with fence lines",
+			path:     "main.go",
+			response: rawString,
+			want:     rawString,
+		},
+		{name: "empty", path: "main.go", response: "```go\n```\n", err: edit.ErrEmptyResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := edit.ExtractCode(tt.response, tt.path)
+			if tt.err != nil {
+				assert.Assert(t, errors.Is(err, tt.err), "Expected %v, got: %v", tt.err, err)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Assert(t, cmp.Equal(got, tt.want))
+		})
+	}
+}
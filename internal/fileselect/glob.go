@@ -0,0 +1,57 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package fileselect
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether a slash separated path matches a glob pattern.
+// Besides the path.Match syntax within a segment, a "**" segment matches
+// zero or more whole segments.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// HasGlobMeta reports whether the pattern uses any glob syntax.
+func HasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+func matchSegments(patterns, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			// Collapse repeated ** and try every possible number of skipped segments
+			for len(patterns) > 0 && patterns[0] == "**" {
+				patterns = patterns[1:]
+			}
+
+			if len(patterns) == 0 {
+				return true
+			}
+
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(patterns, names[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(patterns[0], names[0]); err != nil || !ok {
+			return false
+		}
+
+		patterns, names = patterns[1:], names[1:]
+	}
+
+	return len(names) == 0
+}
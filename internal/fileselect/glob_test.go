@@ -0,0 +1,66 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package fileselect_test
+
+import (
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/fileselect"
+	"gotest.tools/v3/assert"
+)
+
+// TestMatchGlob verifies single segment wildcards and recursive ** segments.
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "internal/cmd/code.go", true},
+		{"internal/**/*.go", "internal/code.go", true},
+		{"internal/**/*.go", "internal/cmd/code.go", true},
+		{"internal/**/*.go", "cmd/code.go", false},
+		{"internal/**", "internal/cmd/code.go", true},
+		{"internal/*_test.go", "internal/code_test.go", true},
+		{"internal/*_test.go", "internal/code.go", false},
+		{"[ab].go", "a.go", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, fileselect.MatchGlob(tt.pattern, tt.name), tt.want, "%s against %s", tt.pattern, tt.name)
+	}
+}
+
+// TestIgnore verifies gitignore rules, negation and nested rule files.
+func TestIgnore(t *testing.T) {
+	ig := &fileselect.Ignore{}
+	ig.AddRules("", "# comment\n*.log\n!keep.log\nbuild/\n/root.txt\n")
+	ig.AddRules("sub", "local.txt\n")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"root.txt", false, true},
+		{"sub/root.txt", false, false},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, ig.Ignored(tt.path, tt.isDir), tt.want, tt.path)
+	}
+
+	assert.Assert(t, ig.IgnoredWithParents("build/out/app.bin", false))
+}
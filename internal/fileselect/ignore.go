@@ -0,0 +1,123 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package fileselect
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is a single line of a .gitignore style file.
+type ignoreRule struct {
+	base    string // slash separated directory the rule file lives in, "" for the root
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// Ignore decides which paths are excluded by .gitignore style rules.
+// Rules are checked in the order they were added and the last match wins.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+// AddRules parses .gitignore style content whose patterns are relative to base,
+// a slash separated path from the root.
+func (ig *Ignore) AddRules(base, content string) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+
+		line = strings.TrimPrefix(line, `\`)
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// Patterns without a slash match at any depth below the base
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+
+		rule.pattern = line
+		ig.rules = append(ig.rules, rule)
+	}
+}
+
+// AddFile adds the rules from a file if it exists.
+func (ig *Ignore) AddFile(base, filePath string) error {
+	// nolint:gosec // Why: ignore files are read from inside the project
+	content, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	ig.AddRules(base, string(content))
+
+	return nil
+}
+
+// Ignored reports whether the slash separated path, relative to the root, is ignored.
+func (ig *Ignore) Ignored(relPath string, isDir bool) bool {
+	ignored := false
+
+	for _, rule := range ig.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		name := relPath
+		if rule.base != "" {
+			if !strings.HasPrefix(relPath, rule.base+"/") {
+				continue
+			}
+
+			name = strings.TrimPrefix(relPath, rule.base+"/")
+		}
+
+		if MatchGlob(rule.pattern, name) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
+
+// IgnoredWithParents reports whether the path or any of its parent directories is ignored.
+func (ig *Ignore) IgnoredWithParents(relPath string, isDir bool) bool {
+	for dir := path.Dir(relPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if ig.Ignored(dir, true) {
+			return true
+		}
+	}
+
+	return ig.Ignored(relPath, isDir)
+}
+
+// toSlashRel returns target relative to root with forward slashes.
+func toSlashRel(root, target string) (string, error) {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+// Package fileselect expands file arguments such as globs and directories
+// into the files a command works on.
+package fileselect
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/git"
+)
+
+// Selection errors.
+var (
+	ErrNoMatches    = errors.New("pattern matched no files")
+	ErrNotGitRepo   = errors.New("--changed requires a git repository")
+	ErrWalkFailed   = errors.New("failed to list files")
+	ErrIgnoreFailed = errors.New("failed to read ignore file")
+)
+
+// Ignore file names.
+const (
+	gitIgnoreFileName = ".gitignore"
+	caIgnoreFileName  = ".caignore"
+)
+
+// Paths that are never selected by a glob or directory.
+const builtinIgnores = ".git/\n.ca_sessions/\n.ca_session.json\n"
+
+// Options describe which files to select.
+type Options struct {
+	Root     string   // Project root, ignore files are read from here down
+	BaseDir  string   // Directory relative patterns are resolved from
+	Patterns []string // Files, directories or globs such as internal/**/*.go
+	Exclude  []string // Globs or directories to leave out
+	Changed  bool     // Add files that differ from HEAD
+}
+
+// selector holds the state of a single Select call.
+type selector struct {
+	opts   *Options
+	ignore *Ignore
+	files  []string // Every non-ignored file under the root, loaded on first use
+}
+
+// Select returns the absolute paths of the selected files, in the order the patterns
+// were given and without duplicates. Globs and directories skip files ignored by
+// .gitignore or .caignore; files named literally are always selected.
+func Select(opts *Options) ([]string, error) {
+	s := &selector{opts: opts, ignore: &Ignore{}}
+	s.ignore.AddRules("", builtinIgnores)
+
+	if err := s.ignore.AddFile("", filepath.Join(opts.Root, gitIgnoreFileName)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIgnoreFailed, err)
+	}
+
+	if err := s.ignore.AddFile("", filepath.Join(opts.Root, caIgnoreFileName)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIgnoreFailed, err)
+	}
+
+	selected := []string{}
+
+	for _, pattern := range opts.Patterns {
+		matches, err := s.expand(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		selected = append(selected, matches...)
+	}
+
+	if opts.Changed {
+		changed, err := s.changed()
+		if err != nil {
+			return nil, err
+		}
+
+		selected = append(selected, changed...)
+	}
+
+	return s.finish(selected)
+}
+
+// expand turns one pattern into root relative, slash separated paths.
+func (s *selector) expand(pattern string) ([]string, error) {
+	rel, err := s.relToRoot(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if HasGlobMeta(rel) {
+		return s.walkMatches(pattern, func(file string) bool { return MatchGlob(rel, file) })
+	}
+
+	info, err := os.Stat(filepath.Join(s.opts.Root, filepath.FromSlash(rel)))
+	if err != nil || !info.IsDir() {
+		// Literal files are passed through so later validation can report them
+		return []string{rel}, nil
+	}
+
+	if rel == "." {
+		return s.walkMatches(pattern, func(string) bool { return true })
+	}
+
+	return s.walkMatches(pattern, func(file string) bool { return strings.HasPrefix(file, rel+"/") })
+}
+
+// walkMatches returns every non-ignored file accepted by match.
+func (s *selector) walkMatches(pattern string, match func(file string) bool) ([]string, error) {
+	if s.files == nil {
+		if err := s.walk(); err != nil {
+			return nil, err
+		}
+	}
+
+	matches := []string{}
+
+	for _, file := range s.files {
+		if match(file) {
+			matches = append(matches, file)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatches, pattern)
+	}
+
+	return matches, nil
+}
+
+// walk lists the non-ignored files under the root, applying nested .gitignore files
+// to the directories they live in.
+func (s *selector) walk() error {
+	s.files = []string{}
+
+	err := filepath.WalkDir(s.opts.Root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := toSlashRel(s.opts.Root, filePath)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." {
+			return nil
+		}
+
+		if s.ignore.Ignored(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() {
+			return s.ignore.AddFile(rel, filepath.Join(filePath, gitIgnoreFileName))
+		}
+
+		if d.Type().IsRegular() {
+			s.files = append(s.files, rel)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWalkFailed, err)
+	}
+
+	return nil
+}
+
+// changed returns the existing, non-ignored files that differ from HEAD or are untracked.
+func (s *selector) changed() ([]string, error) {
+	if !git.IsRepository(s.opts.Root) {
+		return nil, ErrNotGitRepo
+	}
+
+	statuses, err := git.Status(s.opts.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+
+	for _, status := range statuses {
+		rel := filepath.ToSlash(status.Path)
+		if strings.HasPrefix(rel, "../") {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(s.opts.Root, status.Path))
+		if err != nil || !info.Mode().IsRegular() {
+			// Deleted files have nothing left to edit
+			continue
+		}
+
+		if !s.ignore.IgnoredWithParents(rel, false) {
+			files = append(files, rel)
+		}
+	}
+
+	return files, nil
+}
+
+// finish removes excluded and duplicate paths and makes them absolute.
+func (s *selector) finish(selected []string) ([]string, error) {
+	excludes := make([]string, 0, len(s.opts.Exclude))
+
+	for _, pattern := range s.opts.Exclude {
+		rel, err := s.relToRoot(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		excludes = append(excludes, rel)
+	}
+
+	seen := map[string]bool{}
+	files := []string{}
+
+	for _, rel := range selected {
+		if seen[rel] || excluded(rel, excludes) {
+			continue
+		}
+
+		seen[rel] = true
+		files = append(files, filepath.Join(s.opts.Root, filepath.FromSlash(rel)))
+	}
+
+	return files, nil
+}
+
+// excluded reports whether the path matches an exclude glob or sits in an excluded directory.
+func excluded(rel string, excludes []string) bool {
+	for _, exclude := range excludes {
+		if MatchGlob(exclude, rel) || strings.HasPrefix(rel, exclude+"/") || exclude == "." {
+			return true
+		}
+
+		// Patterns without a slash also match file names at any depth, like .gitignore
+		if !strings.Contains(exclude, "/") && MatchGlob(exclude, path.Base(rel)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// relToRoot resolves a pattern from the base directory and makes it relative to the root.
+func (s *selector) relToRoot(pattern string) (string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(s.opts.BaseDir, pattern)
+	}
+
+	return toSlashRel(s.opts.Root, filepath.Clean(pattern))
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package fileselect_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/fileselect"
+	"gotest.tools/v3/assert"
+)
+
+// setupTree creates files relative to a temporary root.
+func setupTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+
+	for name, content := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		assert.NilError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		assert.NilError(t, os.WriteFile(filePath, []byte(content), 0600))
+	}
+
+	return root
+}
+
+// relPaths makes selected paths relative to root for comparison.
+func relPaths(t *testing.T, root string, files []string) []string {
+	rels := make([]string, 0, len(files))
+
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		assert.NilError(t, err)
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+
+	return rels
+}
+
+// TestSelect verifies how patterns expand and which files are left out.
+func TestSelect(t *testing.T) {
+	root := setupTree(t, map[string]string{
+		".gitignore":                "*.log\nvendor/\n",
+		".caignore":                 "internal/gen/\n",
+		"main.go":                   "",
+		"app.log":                   "",
+		"internal/cmd/code.go":      "",
+		"internal/cmd/code_test.go": "",
+		"internal/gen/gen.go":       "",
+		"internal/.gitignore":       "secret.go\n",
+		"internal/secret.go":        "",
+		"vendor/lib/lib.go":         "",
+		".ca_sessions/x.json":       "",
+	})
+
+	tests := []struct {
+		name     string
+		baseDir  string
+		patterns []string
+		exclude  []string
+		want     []string
+	}{
+		{
+			name:     "recursive glob",
+			patterns: []string{"**/*.go"},
+			want:     []string{"internal/cmd/code.go", "internal/cmd/code_test.go", "main.go"},
+		},
+		{
+			name:     "directory",
+			patterns: []string{"internal"},
+			want:     []string{"internal/.gitignore", "internal/cmd/code.go", "internal/cmd/code_test.go"},
+		},
+		{
+			name:     "exclude glob without slash",
+			patterns: []string{"internal/**/*.go"},
+			exclude:  []string{"*_test.go"},
+			want:     []string{"internal/cmd/code.go"},
+		},
+		{
+			name:     "literal ignored file and duplicates",
+			patterns: []string{"app.log", "main.go", "./main.go"},
+			want:     []string{"app.log", "main.go"},
+		},
+		{
+			name:     "relative to base dir",
+			baseDir:  "internal",
+			patterns: []string{"cmd/*.go"},
+			exclude:  []string{"cmd/code_test.go"},
+			want:     []string{"internal/cmd/code.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := fileselect.Select(&fileselect.Options{
+				Root:     root,
+				BaseDir:  filepath.Join(root, tt.baseDir),
+				Patterns: tt.patterns,
+				Exclude:  tt.exclude,
+			})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, relPaths(t, root, files), tt.want)
+		})
+	}
+}
+
+// TestSelect_NoMatches ensures a glob that matches nothing is reported.
+func TestSelect_NoMatches(t *testing.T) {
+	root := setupTree(t, map[string]string{"main.go": ""})
+
+	_, err := fileselect.Select(&fileselect.Options{Root: root, BaseDir: root, Patterns: []string{"**/*.rs"}})
+	assert.Assert(t, errors.Is(err, fileselect.ErrNoMatches), "Expected ErrNoMatches, got: %v", err)
+}
+
+// TestSelect_Changed verifies --changed picks up modified and untracked files only.
+func TestSelect_Changed(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := setupTree(t, map[string]string{
+		".gitignore": "*.log\n",
+		"main.go":    "package main\n",
+		"util.go":    "package main\n",
+	})
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		// nolint:gosec // Why: test code
+		out, err := exec.Command("git", append([]string{"-C", root}, args...)...).CombinedOutput()
+		assert.NilError(t, err, string(out))
+	}
+
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "new.go"), []byte("package main\n"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "debug.log"), []byte("log\n"), 0600))
+	assert.NilError(t, os.Remove(filepath.Join(root, "util.go")))
+
+	files, err := fileselect.Select(&fileselect.Options{Root: root, BaseDir: root, Changed: true})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, relPaths(t, root, files), []string{"main.go", "new.go"})
+
+	_, err = fileselect.Select(&fileselect.Options{Root: t.TempDir(), Changed: true})
+	assert.Assert(t, errors.Is(err, fileselect.ErrNotGitRepo), "Expected ErrNotGitRepo, got: %v", err)
+}
@@ -0,0 +1,13 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package filetx
+
+// SetRename replaces the rename function and returns a func restoring the original.
+func SetRename(fn func(oldpath, newpath string) error) func() {
+	original := rename
+	rename = fn
+
+	return func() { rename = original }
+}
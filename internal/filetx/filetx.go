@@ -0,0 +1,231 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+// Package filetx writes a group of files as a single transaction.
+package filetx
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Transaction errors.
+var (
+	ErrStageFailed   = errors.New("failed to stage file")
+	ErrCommitFailed  = errors.New("failed to commit file")
+	ErrRestoreFailed = errors.New("failed to restore file after a failed commit")
+	ErrCommitted     = errors.New("transaction already committed")
+)
+
+// Permissions for files and directories created by a transaction.
+const (
+	newFilePerm = 0600
+	newDirPerm  = 0750
+)
+
+// rename is swapped out in tests to simulate failures part way through a commit.
+var rename = os.Rename
+
+// op is a pending change to a single file.
+type op struct {
+	path    string
+	content []byte
+	remove  bool
+
+	// Filled in while staging
+	tempPath string
+	original []byte
+	existed  bool
+	mode     fs.FileMode
+	applied  bool
+}
+
+// Tx is a set of file writes and deletes that are applied all together or not at all.
+type Tx struct {
+	ops       []*op
+	committed bool
+}
+
+// New returns an empty transaction.
+func New() *Tx {
+	return &Tx{}
+}
+
+// Write adds writing content to path to the transaction.
+func (t *Tx) Write(path string, content []byte) {
+	t.ops = append(t.ops, &op{path: path, content: content})
+}
+
+// Delete adds removing path to the transaction.
+func (t *Tx) Delete(path string) {
+	t.ops = append(t.ops, &op{path: path, remove: true})
+}
+
+// Commit stages every write to a temp file next to its target, then renames them
+// into place. If any step fails the original files are put back.
+func (t *Tx) Commit() error {
+	if t.committed {
+		return ErrCommitted
+	}
+
+	t.committed = true
+
+	if err := t.stage(); err != nil {
+		return errors.Join(err, t.cleanup())
+	}
+
+	for _, o := range t.ops {
+		if err := o.apply(); err != nil {
+			return errors.Join(fmt.Errorf("%w: %s: %v", ErrCommitFailed, o.path, err), t.restore(), t.cleanup())
+		}
+	}
+
+	return nil
+}
+
+// stage records the original content of every target and writes new content to temp files.
+func (t *Tx) stage() error {
+	for _, o := range t.ops {
+		info, err := os.Stat(o.path)
+
+		switch {
+		case err == nil:
+			// nolint:gosec // Why: callers validate the paths they add
+			o.original, err = os.ReadFile(o.path)
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrStageFailed, o.path, err)
+			}
+
+			o.existed, o.mode = true, info.Mode().Perm()
+		case os.IsNotExist(err):
+			o.mode = newFilePerm
+		default:
+			return fmt.Errorf("%w: %s: %v", ErrStageFailed, o.path, err)
+		}
+
+		if o.remove {
+			continue
+		}
+
+		o.tempPath, err = writeTemp(o.path, o.content, o.mode)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrStageFailed, o.path, err)
+		}
+	}
+
+	return nil
+}
+
+// apply moves the staged change into place.
+func (o *op) apply() error {
+	if o.remove {
+		if !o.existed {
+			return nil
+		}
+
+		if err := os.Remove(o.path); err != nil {
+			return err
+		}
+
+		o.applied = true
+
+		return nil
+	}
+
+	if err := rename(o.tempPath, o.path); err != nil {
+		return err
+	}
+
+	o.tempPath = ""
+	o.applied = true
+
+	return nil
+}
+
+// restore puts back the original content of every applied change.
+func (t *Tx) restore() error {
+	errs := []error{}
+
+	for _, o := range t.ops {
+		if !o.applied {
+			continue
+		}
+
+		var err error
+		if o.existed {
+			err = restoreContent(o.path, o.original, o.mode)
+		} else {
+			err = os.Remove(o.path)
+		}
+
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("%w: %s: %v", ErrRestoreFailed, o.path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// cleanup removes any temp files left behind.
+func (t *Tx) cleanup() error {
+	errs := []error{}
+
+	for _, o := range t.ops {
+		if o.tempPath == "" {
+			continue
+		}
+
+		if err := os.Remove(o.tempPath); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+
+		o.tempPath = ""
+	}
+
+	return errors.Join(errs...)
+}
+
+// restoreContent atomically writes the original content back to path.
+func restoreContent(path string, content []byte, mode fs.FileMode) error {
+	tempPath, err := writeTemp(path, content, mode)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return nil
+}
+
+// writeTemp writes content to a new temp file in the same directory as path,
+// so it can later be renamed over path atomically.
+func writeTemp(path string, content []byte, mode fs.FileMode) (string, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, newDirPerm); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".ca-tmp-")
+	if err != nil {
+		return "", err
+	}
+
+	_, writeErr := tmp.Write(content)
+	syncErr := tmp.Sync()
+	closeErr := tmp.Close()
+	chmodErr := os.Chmod(tmp.Name(), mode)
+
+	if err := errors.Join(writeErr, syncErr, closeErr, chmodErr); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
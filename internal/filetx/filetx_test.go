@@ -0,0 +1,94 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package filetx_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/filetx"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+var errInjected = errors.New("injected rename failure")
+
+func writeFile(t *testing.T, path, content string) {
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func readFile(t *testing.T, path string) string {
+	// nolint:gosec // Why: test code
+	content, err := os.ReadFile(path)
+	assert.NilError(t, err)
+
+	return string(content)
+}
+
+// assertNoTempFiles ensures a transaction left nothing behind in dir.
+func assertNoTempFiles(t *testing.T, dir string, want int) {
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(entries, want), "Unexpected files left in %s: %v", dir, entries)
+}
+
+// TestCommit_AppliesAll verifies writes, creates and deletes land together.
+func TestCommit_AppliesAll(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.go"), "old a")
+	writeFile(t, filepath.Join(dir, "b.go"), "old b")
+
+	tx := filetx.New()
+	tx.Write(filepath.Join(dir, "a.go"), []byte("new a"))
+	tx.Write(filepath.Join(dir, "sub", "c.go"), []byte("new c"))
+	tx.Delete(filepath.Join(dir, "b.go"))
+	assert.NilError(t, tx.Commit())
+
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "a.go")), "new a"))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "sub", "c.go")), "new c"))
+
+	_, err := os.Stat(filepath.Join(dir, "b.go"))
+	assert.Assert(t, os.IsNotExist(err), "Expected b.go to be deleted.")
+	assertNoTempFiles(t, dir, 2)
+
+	assert.Assert(t, errors.Is(tx.Commit(), filetx.ErrCommitted))
+}
+
+// TestCommit_RestoresOnFailure verifies a failure part way through puts back every original.
+func TestCommit_RestoresOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.go"), "old a")
+	writeFile(t, filepath.Join(dir, "b.go"), "old b")
+	writeFile(t, filepath.Join(dir, "d.go"), "old d")
+
+	calls := 0
+	defer filetx.SetRename(func(oldpath, newpath string) error {
+		calls++
+		if calls == 3 {
+			return errInjected
+		}
+
+		return os.Rename(oldpath, newpath)
+	})()
+
+	tx := filetx.New()
+	tx.Write(filepath.Join(dir, "a.go"), []byte("new a"))
+	tx.Delete(filepath.Join(dir, "d.go"))
+	tx.Write(filepath.Join(dir, "c.go"), []byte("new c"))
+	tx.Write(filepath.Join(dir, "b.go"), []byte("new b"))
+
+	err := tx.Commit()
+	assert.Assert(t, errors.Is(err, filetx.ErrCommitFailed), "Expected ErrCommitFailed, got: %v", err)
+
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "a.go")), "old a"))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "b.go")), "old b"))
+	assert.Assert(t, cmp.Equal(readFile(t, filepath.Join(dir, "d.go")), "old d"))
+
+	_, err = os.Stat(filepath.Join(dir, "c.go"))
+	assert.Assert(t, os.IsNotExist(err), "Expected created c.go to be removed.")
+	assertNoTempFiles(t, dir, 3)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package git
+
+// CurrentBranch returns the name of the checked out branch, or an empty string when
+// HEAD is detached.
+func CurrentBranch(dir string) (string, error) {
+	out, err := run(dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		// symbolic-ref fails without output when HEAD is detached
+		if _, topErr := TopLevel(dir); topErr == nil {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return out, nil
+}
+
+// CreateBranch creates branch at HEAD and checks it out, keeping uncommitted changes.
+func CreateBranch(dir, branch string) error {
+	_, err := run(dir, "switch", "--create", branch)
+	return err
+}
+
+// Switch checks out branch. With discardChanges uncommitted changes to tracked files are thrown away.
+func Switch(dir, branch string, discardChanges bool) error {
+	args := []string{"switch", branch}
+	if discardChanges {
+		args = []string{"switch", "--discard-changes", branch}
+	}
+
+	_, err := run(dir, args...)
+
+	return err
+}
+
+// DeleteBranch deletes branch even when it hasn't been merged.
+func DeleteBranch(dir, branch string) error {
+	_, err := run(dir, "branch", "--delete", "--force", branch)
+	return err
+}
+
+// AddWorktree creates branch at HEAD and checks it out in a new work tree at path.
+func AddWorktree(dir, path, branch string) error {
+	_, err := run(dir, "worktree", "add", "--quiet", "-b", branch, path)
+	return err
+}
+
+// RemoveWorktree removes the work tree at path along with any changes in it.
+func RemoveWorktree(dir, path string) error {
+	_, err := run(dir, "worktree", "remove", "--force", path)
+	return err
+}
+
+// MergeSquash stages the changes of branch on top of the checked out branch without committing them.
+func MergeSquash(dir, branch string) error {
+	_, err := run(dir, "merge", "--squash", branch)
+	return err
+}
+
+// MergeFastForward moves the checked out branch forward to branch, failing when they have diverged.
+func MergeFastForward(dir, branch string) error {
+	_, err := run(dir, "merge", "--ff-only", "--quiet", branch)
+	return err
+}
+
+// Rebase replays the commits of the checked out branch on top of upstream.
+func Rebase(dir, upstream string) error {
+	_, err := run(dir, "rebase", "--quiet", upstream)
+	return err
+}
+
+// TrackedChanges returns the paths, relative to dir, of tracked files with uncommitted changes.
+func TrackedChanges(dir string) ([]string, error) {
+	statuses, err := Status(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+
+	for _, status := range statuses {
+		if !status.Untracked() {
+			files = append(files, status.Path)
+		}
+	}
+
+	return files, nil
+}
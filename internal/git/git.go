@@ -0,0 +1,200 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+// Package git wraps the git commands used to track the state of a project.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Git errors.
+var (
+	ErrCommandFailed = errors.New("git command failed")
+)
+
+// run executes git in dir and returns its trimmed stdout.
+func run(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	// nolint:gosec // Why: arguments are built by this package, not taken from input
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: git %s: %v: %s", ErrCommandFailed,
+			strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// IsRepository reports whether dir is inside a git work tree.
+func IsRepository(dir string) bool {
+	out, err := run(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && out == "true"
+}
+
+// TopLevel returns the root directory of the work tree containing dir.
+func TopLevel(dir string) (string, error) {
+	return run(dir, "rev-parse", "--show-toplevel")
+}
+
+// HeadCommit returns the commit hash of HEAD, or an empty string when the
+// repository has no commits yet.
+func HeadCommit(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		// rev-parse --verify fails without output when HEAD is unborn
+		if _, topErr := TopLevel(dir); topErr == nil {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return out, nil
+}
+
+// FileStatus is the porcelain status of a single path.
+type FileStatus struct {
+	Path     string
+	Index    byte
+	WorkTree byte
+}
+
+// Untracked reports whether the file is not tracked by git, including files it ignores.
+func (f FileStatus) Untracked() bool {
+	return (f.Index == '?' && f.WorkTree == '?') || f.Ignored()
+}
+
+// Ignored reports whether the file is untracked and matched by a gitignore rule.
+func (f FileStatus) Ignored() bool {
+	return f.Index == '!' && f.WorkTree == '!'
+}
+
+// Status returns the status of every changed or untracked file, with paths relative to dir.
+func Status(dir string, paths ...string) ([]FileStatus, error) {
+	return status(dir, []string{"status", "--porcelain=v1", "-z", "--untracked-files=all"}, paths)
+}
+
+// StatusWithIgnored is Status that also reports the files git ignores.
+func StatusWithIgnored(dir string, paths ...string) ([]FileStatus, error) {
+	return status(dir, []string{"status", "--porcelain=v1", "-z", "--untracked-files=all", "--ignored"}, paths)
+}
+
+// status runs git status with args, limited to paths when there are any.
+func status(dir string, args, paths []string) ([]FileStatus, error) {
+	topLevel, err := TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+
+	out, err := run(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// git reports the top level with symlinks resolved, so resolve dir the same way
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	return parseStatus(out, topLevel, dir)
+}
+
+// parseStatus parses NUL separated porcelain v1 output.
+func parseStatus(out, topLevel, dir string) ([]FileStatus, error) {
+	statuses := []FileStatus{}
+	entries := strings.Split(out, "\x00")
+
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		status := FileStatus{Index: entry[0], WorkTree: entry[1]}
+
+		relPath, err := filepath.Rel(dir, filepath.Join(topLevel, filepath.FromSlash(entry[3:])))
+		if err != nil {
+			return nil, err
+		}
+
+		status.Path = relPath
+		statuses = append(statuses, status)
+
+		// Renames and copies are followed by the original path
+		if status.Index == 'R' || status.Index == 'C' {
+			i++
+		}
+	}
+
+	return statuses, nil
+}
+
+// UncommittedFiles returns the paths, relative to dir, of files that differ from HEAD or are untracked.
+func UncommittedFiles(dir string) ([]string, error) {
+	statuses, err := Status(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		files = append(files, status.Path)
+	}
+
+	return files, nil
+}
+
+// Add stages the current content of the paths, including deletions.
+func Add(dir string, paths ...string) error {
+	_, err := run(dir, append([]string{"add", "--all", "--"}, paths...)...)
+	return err
+}
+
+// SaveIndex writes the index to a tree object and returns its hash, so that it can
+// later be put back with RestoreIndex.
+func SaveIndex(dir string) (string, error) {
+	return run(dir, "write-tree")
+}
+
+// RestoreIndex replaces the index with the tree saved by SaveIndex, leaving the work
+// tree alone.
+func RestoreIndex(dir, tree string) error {
+	_, err := run(dir, "read-tree", tree)
+	return err
+}
+
+// StagedDiff returns the staged changes to the paths as a unified diff.
+func StagedDiff(dir string, paths ...string) (string, error) {
+	return run(dir, append([]string{"diff", "--cached", "--"}, paths...)...)
+}
+
+// Commit commits only the given paths with the message, leaving anything else that is
+// staged for later, and returns the hash of the new commit. Without paths everything
+// staged is committed.
+func Commit(dir, message string, paths ...string) (string, error) {
+	args := []string{"commit", "--quiet", "--message", message}
+	if len(paths) > 0 {
+		args = append(append(args, "--only", "--"), paths...)
+	}
+
+	if _, err := run(dir, args...); err != nil {
+		return "", err
+	}
+
+	return HeadCommit(dir)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/git"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// runGit runs a git command in dir for test setup.
+func runGit(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+
+	// nolint:gosec // Why: test code
+	out, err := exec.Command("git", args...).CombinedOutput()
+	assert.NilError(t, err, string(out))
+}
+
+// setupRepo creates a repository with a single committed file.
+func setupRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0600))
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+
+	return dir
+}
+
+// TestHeadCommit verifies the HEAD commit is reported for repositories only.
+func TestHeadCommit(t *testing.T) {
+	dir := setupRepo(t)
+
+	assert.Assert(t, git.IsRepository(dir))
+
+	commit, err := git.HeadCommit(dir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(commit, 40))
+
+	assert.Assert(t, !git.IsRepository(t.TempDir()))
+}
+
+// TestUncommittedFiles verifies modified and untracked files are reported relative to dir.
+func TestUncommittedFiles(t *testing.T) {
+	dir := setupRepo(t)
+
+	files, err := git.UncommittedFiles(dir)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(files, 0))
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n"), 0600))
+	assert.NilError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0750))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "pkg", "new.go"), []byte("package pkg\n"), 0600))
+
+	files, err = git.UncommittedFiles(dir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, []string{"main.go", filepath.Join("pkg", "new.go")})
+
+	files, err = git.UncommittedFiles(filepath.Join(dir, "pkg"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, []string{filepath.Join("..", "main.go"), "new.go"})
+}
+
+// TestCommit verifies only the given paths are committed, including new and deleted files.
+func TestCommit(t *testing.T) {
+	dir := setupRepo(t)
+	runGit(t, dir, "config", "user.name", "test")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n\nfunc main() {}\n"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package main\n"), 0600))
+	assert.NilError(t, os.Remove(filepath.Join(dir, "main.go")))
+
+	assert.NilError(t, git.Add(dir, "new.go", "main.go"))
+
+	diff, err := git.StagedDiff(dir, "new.go", "main.go")
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(diff, "+++ b/new.go"))
+	assert.Assert(t, cmp.Contains(diff, "--- a/main.go"))
+
+	commit, err := git.Commit(dir, "feat: replace main", "new.go", "main.go")
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(commit, 40))
+
+	files, err := git.UncommittedFiles(dir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, []string{"other.go"})
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+// Package repomap indexes the Go symbols of a project so prompts can describe
+// code that lives outside the files being edited.
+package repomap
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/chrisrob11/codeassistant/internal/fileselect"
+)
+
+// Index errors.
+var (
+	ErrIndexFailed = errors.New("failed to index go files")
+)
+
+// Symbol kinds.
+const (
+	KindFunc   = "func"
+	KindMethod = "method"
+	KindType   = "type"
+	KindConst  = "const"
+	KindVar    = "var"
+)
+
+// Files and directories that are not indexed.
+var indexExcludes = []string{"*_test.go", "**/vendor/**", "**/testdata/**"}
+
+// Symbol is a top-level declaration in a Go file.
+type Symbol struct {
+	Dir       string // Slash separated package directory relative to the root
+	Package   string // Package name
+	Name      string // Identifier, or Type.Method for methods
+	Kind      string
+	Signature string // Declaration without its body, on one line
+	File      string // Slash separated path relative to the root
+	Line      int
+}
+
+// Exported reports whether code in other packages can use the symbol.
+func (s Symbol) Exported() bool {
+	return token.IsExported(s.Name[strings.LastIndex(s.Name, ".")+1:])
+}
+
+// Index holds the symbols of every indexed file.
+type Index struct {
+	Symbols []Symbol
+}
+
+// Build parses the non-test Go files under root, skipping ignored files. Files that
+// don't parse are left out rather than failing the whole index.
+func Build(root string) (*Index, error) {
+	files, err := fileselect.Select(&fileselect.Options{
+		Root:     root,
+		BaseDir:  root,
+		Patterns: []string{"**/*.go"},
+		Exclude:  indexExcludes,
+	})
+	if errors.Is(err, fileselect.ErrNoMatches) {
+		return &Index{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIndexFailed, err)
+	}
+
+	index := &Index{}
+	fset := token.NewFileSet()
+
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrIndexFailed, err)
+		}
+
+		parsed, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+
+		index.Symbols = append(index.Symbols, fileSymbols(fset, filepath.ToSlash(rel), parsed)...)
+	}
+
+	return index, nil
+}
+
+// fileSymbols lists the top-level declarations of a parsed file.
+func fileSymbols(fset *token.FileSet, rel string, file *ast.File) []Symbol {
+	symbols := []Symbol{}
+	base := Symbol{Dir: path.Dir(rel), Package: file.Name.Name, File: rel}
+
+	add := func(name, kind string, node ast.Node, pos token.Pos) {
+		symbol := base
+		symbol.Name, symbol.Kind = name, kind
+		symbol.Signature = oneLine(nodeString(fset, node))
+		symbol.Line = fset.Position(pos).Line
+		symbols = append(symbols, symbol)
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			// Print the signature only
+			signature := *decl
+			signature.Body, signature.Doc = nil, nil
+
+			if decl.Recv == nil {
+				add(decl.Name.Name, KindFunc, &signature, decl.Pos())
+			} else {
+				add(receiverType(decl.Recv)+"."+decl.Name.Name, KindMethod, &signature, decl.Pos())
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					add(spec.Name.Name, KindType, &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{spec}}, spec.Pos())
+				case *ast.ValueSpec:
+					kind := KindVar
+					if decl.Tok == token.CONST {
+						kind = KindConst
+					}
+
+					for _, name := range spec.Names {
+						if name.Name != "_" {
+							add(name.Name, kind, &ast.GenDecl{Tok: decl.Tok, Specs: []ast.Spec{spec}}, name.Pos())
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return symbols
+}
+
+// receiverType returns the type name of a method receiver without pointers or type parameters.
+func receiverType(recv *ast.FieldList) string {
+	expr := recv.List[0].Type
+
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// nodeString prints a node as Go source.
+func nodeString(fset *token.FileSet, node ast.Node) string {
+	var b strings.Builder
+	if err := format.Node(&b, fset, node); err != nil {
+		return ""
+	}
+
+	return b.String()
+}
+
+// oneLine collapses a printed declaration onto a single line.
+func oneLine(source string) string {
+	var b strings.Builder
+
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+
+		current := b.String()
+
+		switch {
+		case current == "":
+		case strings.HasSuffix(current, "{") || strings.HasSuffix(current, "(") ||
+			strings.HasPrefix(line, "}") || strings.HasPrefix(line, ")"):
+			b.WriteString(" ")
+		default:
+			b.WriteString("; ")
+		}
+
+		b.WriteString(line)
+	}
+
+	return b.String()
+}
+
+// Focus describes what a prompt is about so the most relevant symbols are kept.
+type Focus struct {
+	Files []string // Slash separated paths, relative to the root, already included in the prompt
+	Text  string   // Prompt and file contents, searched for identifiers they mention
+}
+
+var identPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// Summary lists the symbols most relevant to the focus, grouped by package, within
+// a budget of roughly maxTokens tokens. Symbols from the focus files are left out
+// since their source is already in the prompt. Returns "" when nothing fits.
+func (idx *Index) Summary(focus *Focus, maxTokens int, countTokens func(string) int) string {
+	candidates := idx.rank(focus)
+	selected := []Symbol{}
+	used := 0
+
+	for _, symbol := range candidates {
+		cost := countTokens(symbolLine(symbol)) + 1
+		if used+cost > maxTokens {
+			continue
+		}
+
+		used += cost
+		selected = append(selected, symbol)
+	}
+
+	if len(selected) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Dir != selected[j].Dir {
+			return selected[i].Dir < selected[j].Dir
+		}
+
+		if selected[i].File != selected[j].File {
+			return selected[i].File < selected[j].File
+		}
+
+		return selected[i].Line < selected[j].Line
+	})
+
+	var b strings.Builder
+
+	for i, symbol := range selected {
+		if i == 0 || symbol.Dir != selected[i-1].Dir {
+			fmt.Fprintf(&b, "package %s (%s)\n", symbol.Package, symbol.Dir)
+		}
+
+		fmt.Fprintf(&b, "  %s\n", symbolLine(symbol))
+	}
+
+	return b.String()
+}
+
+// rank orders the candidate symbols from most to least relevant. Unexported symbols
+// are only candidates when they share a package with a focus file.
+func (idx *Index) rank(focus *Focus) []Symbol {
+	focusFiles := map[string]bool{}
+	focusDirs := map[string]bool{}
+
+	for _, file := range focus.Files {
+		focusFiles[file] = true
+		focusDirs[path.Dir(file)] = true
+	}
+
+	mentioned := map[string]bool{}
+	for _, ident := range identPattern.FindAllString(focus.Text, -1) {
+		mentioned[ident] = true
+	}
+
+	type scored struct {
+		symbol Symbol
+		score  int
+	}
+
+	candidates := []scored{}
+
+	for _, symbol := range idx.Symbols {
+		sameDir := focusDirs[symbol.Dir]
+		if focusFiles[symbol.File] || (!symbol.Exported() && !sameDir) {
+			continue
+		}
+
+		score := 0
+
+		for _, part := range strings.Split(symbol.Name, ".") {
+			if mentioned[part] {
+				score += 4
+			}
+		}
+
+		if sameDir {
+			score += 2
+		}
+
+		if symbol.Exported() {
+			score++
+		}
+
+		candidates = append(candidates, scored{symbol: symbol, score: score})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	symbols := make([]Symbol, 0, len(candidates))
+	for _, candidate := range candidates {
+		symbols = append(symbols, candidate.symbol)
+	}
+
+	return symbols
+}
+
+// symbolLine renders a symbol with its location.
+func symbolLine(symbol Symbol) string {
+	return fmt.Sprintf("%s // %s:%d", symbol.Signature, path.Base(symbol.File), symbol.Line)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package repomap_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/repomap"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// setupProject writes a small Go project into a temp dir.
+func setupProject(t *testing.T) string {
+	root := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": "module example.com/app\n",
+		"store/store.go": `package store
+
+// Store persists users.
+type Store interface {
+	Get(id int) (*User, error)
+}
+
+type User struct {
+	ID   int
+	Name string
+}
+
+const MaxUsers = 100
+
+func NewMemory() Store { return nil }
+
+func (u *User) Rename(name string) error {
+	u.Name = name
+	return nil
+}
+
+func helper() {}
+`,
+		"store/store_test.go": "package store\n\nfunc TestOnly() {}\n",
+		"api/handler.go":      "package api\n\nfunc Handle() {}\n\nfunc local() {}\n",
+		"api/broken.go":       "package api\n\nfunc {\n",
+	}
+
+	for name, content := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		assert.NilError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		assert.NilError(t, os.WriteFile(filePath, []byte(content), 0600))
+	}
+
+	return root
+}
+
+func countChars(text string) int {
+	return len(text)
+}
+
+// TestBuild verifies declarations are indexed with signatures and locations.
+func TestBuild(t *testing.T) {
+	index, err := repomap.Build(setupProject(t))
+	assert.NilError(t, err)
+
+	symbols := map[string]repomap.Symbol{}
+	for _, symbol := range index.Symbols {
+		symbols[symbol.Name] = symbol
+	}
+
+	assert.Equal(t, symbols["Store"].Signature, "type Store interface { Get(id int) (*User, error) }")
+	assert.Equal(t, symbols["User"].Signature, "type User struct { ID int; Name string }")
+	assert.Equal(t, symbols["User.Rename"].Signature, "func (u *User) Rename(name string) error")
+	assert.Equal(t, symbols["User.Rename"].Kind, repomap.KindMethod)
+	assert.Equal(t, symbols["User.Rename"].Line, 17)
+	assert.Equal(t, symbols["MaxUsers"].Signature, "const MaxUsers = 100")
+	assert.Equal(t, symbols["NewMemory"].File, "store/store.go")
+
+	_, ok := symbols["TestOnly"]
+	assert.Assert(t, !ok, "Test files should not be indexed.")
+}
+
+// TestSummary verifies relevant symbols come first and the budget is respected.
+func TestSummary(t *testing.T) {
+	index, err := repomap.Build(setupProject(t))
+	assert.NilError(t, err)
+
+	summary := index.Summary(&repomap.Focus{
+		Files: []string{"api/handler.go"},
+		Text:  "Use NewMemory to load the user",
+	}, 1000, countChars)
+
+	assert.Assert(t, cmp.Contains(summary, "package store (store)\n"))
+	assert.Assert(t, cmp.Contains(summary, "func NewMemory() Store // store.go:15"))
+	assert.Assert(t, !strings.Contains(summary, "helper"), "Unexported symbols of other packages are left out.")
+	assert.Assert(t, !strings.Contains(summary, "Handle()"), "Symbols of focus files are already in the prompt.")
+
+	small := index.Summary(&repomap.Focus{Text: "NewMemory"}, 40, countChars)
+	assert.Assert(t, cmp.Contains(small, "NewMemory"))
+	assert.Assert(t, !strings.Contains(small, "Rename"))
+
+	assert.Equal(t, index.Summary(&repomap.Focus{}, 1, countChars), "")
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Blob store location, kept inside the session history directory so it
+// survives the archiving of individual sessions.
+const sessionBlobDirName = "blobs"
+
+// Custom blob store errors.
+var (
+	ErrBlobWriteFail   = errors.New("failed to write blob")
+	ErrBlobReadFail    = errors.New("failed to read blob")
+	ErrBlobNotFound    = errors.New("blob not found")
+	ErrBlobInvalidHash = errors.New("invalid blob hash")
+	ErrSnapshotFail    = errors.New("failed to snapshot file")
+)
+
+// FileSnapshot records the content of a file before and after a step.
+// An empty hash means the file did not exist at that point.
+type FileSnapshot struct {
+	Path     string `json:"path"`
+	PreHash  string `json:"pre_hash,omitempty"`
+	PostHash string `json:"post_hash,omitempty"`
+}
+
+// BlobStore is a content-addressed store of file contents.
+type BlobStore struct {
+	dir string
+}
+
+// BuildBlobStorePath is the path to the blob store for a session directory.
+func BuildBlobStorePath(path string) string {
+	return filepath.Join(BuildSessionHistoryPath(path), sessionBlobDirName)
+}
+
+// NewBlobStore returns the blob store belonging to the session directory.
+func NewBlobStore(sessionDir string) *BlobStore {
+	return &BlobStore{dir: BuildBlobStorePath(sessionDir)}
+}
+
+// HashContent returns the hash used to address content in the store.
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Put stores the content if it isn't already present and returns its hash.
+func (b *BlobStore) Put(content []byte) (string, error) {
+	hash := HashContent(content)
+
+	blobPath, err := b.blobPath(hash)
+	if err != nil {
+		return "", err
+	}
+
+	// Content is deduplicated by hash, so an existing blob is already correct
+	if _, err := os.Stat(blobPath); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0750); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBlobWriteFail, err)
+	}
+
+	// Write to a temp file first so a partial blob never has a valid name
+	tmp, err := os.CreateTemp(filepath.Dir(blobPath), ".tmp-")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBlobWriteFail, err)
+	}
+
+	tmpName := tmp.Name()
+
+	_, writeErr := tmp.Write(content)
+	closeErr := tmp.Close()
+
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(tmpName)
+		return "", fmt.Errorf("%w: %v", ErrBlobWriteFail, err)
+	}
+
+	if err := os.Rename(tmpName, blobPath); err != nil {
+		_ = os.Remove(tmpName)
+		return "", fmt.Errorf("%w: %v", ErrBlobWriteFail, err)
+	}
+
+	return hash, nil
+}
+
+// Get returns the content stored under the hash.
+func (b *BlobStore) Get(hash string) ([]byte, error) {
+	blobPath, err := b.blobPath(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec // Why: path is built from a validated hash
+	content, err := os.ReadFile(blobPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, hash)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBlobReadFail, err)
+	}
+
+	return content, nil
+}
+
+// SnapshotFile stores the current content of a file and returns its hash,
+// or an empty hash when the file does not exist.
+func (b *BlobStore) SnapshotFile(path string) (string, error) {
+	// nolint:gosec // Why: callers validate the path before snapshotting
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSnapshotFail, err)
+	}
+
+	return b.Put(content)
+}
+
+// blobPath fans blobs out over subdirectories keyed by the hash prefix.
+func (b *BlobStore) blobPath(hash string) (string, error) {
+	if len(hash) != sha256.Size*2 {
+		return "", fmt.Errorf("%w: %q", ErrBlobInvalidHash, hash)
+	}
+
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", fmt.Errorf("%w: %q", ErrBlobInvalidHash, hash)
+	}
+
+	return filepath.Join(b.dir, hash[:2], hash), nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package session_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/session"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// TestBlobStore_PutGet verifies that stored content can be read back by hash.
+func TestBlobStore_PutGet(t *testing.T) {
+	sessionDir := setupTestEnv(t)
+	store := session.NewBlobStore(sessionDir)
+
+	hash, err := store.Put([]byte("package main\n"))
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Equal(hash, session.HashContent([]byte("package main\n"))))
+
+	content, err := store.Get(hash)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Equal(string(content), "package main\n"))
+}
+
+// TestBlobStore_Deduplicates verifies that identical content is stored once.
+func TestBlobStore_Deduplicates(t *testing.T) {
+	sessionDir := setupTestEnv(t)
+	store := session.NewBlobStore(sessionDir)
+
+	first, err := store.Put([]byte("same"))
+	assert.NilError(t, err)
+
+	second, err := store.Put([]byte("same"))
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Equal(first, second))
+
+	blobs, err := os.ReadDir(filepath.Join(session.BuildBlobStorePath(sessionDir), first[:2]))
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Len(blobs, 1), "Expected a single blob for duplicate content.")
+}
+
+// TestBlobStore_GetMissing ensures unknown and malformed hashes are rejected.
+func TestBlobStore_GetMissing(t *testing.T) {
+	sessionDir := setupTestEnv(t)
+	store := session.NewBlobStore(sessionDir)
+
+	_, err := store.Get(session.HashContent([]byte("never stored")))
+	assert.Assert(t, errors.Is(err, session.ErrBlobNotFound), "Expected ErrBlobNotFound, got: %v", err)
+
+	_, err = store.Get("../../etc/passwd")
+	assert.Assert(t, errors.Is(err, session.ErrBlobInvalidHash), "Expected ErrBlobInvalidHash, got: %v", err)
+}
+
+// TestBlobStore_SnapshotFile verifies snapshots of existing and missing files.
+func TestBlobStore_SnapshotFile(t *testing.T) {
+	sessionDir := setupTestEnv(t)
+	store := session.NewBlobStore(sessionDir)
+
+	filePath := filepath.Join(sessionDir, "main.go")
+	assert.NilError(t, os.WriteFile(filePath, []byte("package main\n"), 0600))
+
+	hash, err := store.SnapshotFile(filePath)
+	assert.NilError(t, err)
+
+	content, err := store.Get(hash)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Equal(string(content), "package main\n"))
+
+	hash, err = store.SnapshotFile(filepath.Join(sessionDir, "missing.go"))
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Equal(hash, ""), "Expected an empty hash for a missing file.")
+}
+
+// TestNewFilesDiff verifies snapshots are classified by how the step changed them.
+func TestNewFilesDiff(t *testing.T) {
+	diff := session.NewFilesDiff([]session.FileSnapshot{
+		{Path: "created.go", PostHash: "b"},
+		{Path: "modified.go", PreHash: "a", PostHash: "b"},
+		{Path: "deleted.go", PreHash: "a"},
+		{Path: "unchanged.go", PreHash: "a", PostHash: "a"},
+	})
+
+	assert.DeepEqual(t, diff.Created, []string{"created.go"})
+	assert.DeepEqual(t, diff.Modified, []string{"modified.go"})
+	assert.DeepEqual(t, diff.Deleted, []string{"deleted.go"})
+	assert.DeepEqual(t, diff.Unchanged, []string{"unchanged.go"})
+}
@@ -20,6 +20,10 @@ import (
 // File Paths.
 const sessionFileName = ".ca_session.json"
 const sessionHistoryDirName = ".ca_sessions"
+const configFileName = ".ca.yaml"
+
+// Files and directories marking the root of a project.
+var rootMarkers = []string{sessionFileName, ".git", configFileName}
 
 // Custom session errors.
 var (
@@ -36,19 +40,59 @@ var (
 	ErrSessionFileExistanceFailed = errors.New("failed to check if current session file exists")
 	ErrSessionDirNotSpecified     = errors.New("failed as the session dir was not specified")
 	ErrSessionNameSpecified       = errors.New("failed as the session name was not specified")
+	ErrStepNotFound               = errors.New("step not found in session")
+	ErrSessionRootResolve         = errors.New("failed to resolve session root directory")
+)
+
+// Step types.
+const (
+	StepTypeCode     = "code"
+	StepTypeRollback = "rollback"
+	StepTypeFix      = "fix"
+	StepTypeSummary  = "summary"
 )
 
 // Command represents the command details associated with a step.
 type Command struct {
-	Prompt string   `json:"prompt"`
-	Files  []string `json:"files"`
+	Prompt       string   `json:"prompt"`
+	Files        []string `json:"files"`
+	ContextFiles []string `json:"context_files,omitempty"`
+	Symbols      []string `json:"symbols,omitempty"`
+	Model        string   `json:"model,omitempty"`
 }
 
 // FilesDiff represents the differences in files during the step.
 type FilesDiff struct {
-	Created  []string `json:"created"`
-	Modified []string `json:"modified"`
-	Deleted  []string `json:"deleted"`
+	Created   []string       `json:"created"`
+	Modified  []string       `json:"modified"`
+	Deleted   []string       `json:"deleted"`
+	Unchanged []string       `json:"unchanged,omitempty"`
+	Snapshots []FileSnapshot `json:"snapshots,omitempty"`
+}
+
+// NewFilesDiff classifies the snapshotted files by how the step changed them.
+func NewFilesDiff(snapshots []FileSnapshot) FilesDiff {
+	diff := FilesDiff{
+		Created:   []string{},
+		Modified:  []string{},
+		Deleted:   []string{},
+		Snapshots: snapshots,
+	}
+
+	for _, snapshot := range snapshots {
+		switch {
+		case snapshot.PreHash == snapshot.PostHash:
+			diff.Unchanged = append(diff.Unchanged, snapshot.Path)
+		case snapshot.PreHash == "":
+			diff.Created = append(diff.Created, snapshot.Path)
+		case snapshot.PostHash == "":
+			diff.Deleted = append(diff.Deleted, snapshot.Path)
+		default:
+			diff.Modified = append(diff.Modified, snapshot.Path)
+		}
+	}
+
+	return diff
 }
 
 // GitState represents the state of the Git repository at a specific point.
@@ -64,27 +108,120 @@ type Git struct {
 	Post GitState `json:"post"`
 }
 
+// Verification statuses.
+const (
+	VerificationPassed = "passed"
+	VerificationFailed = "failed"
+)
+
+// CheckResult is the outcome of one verification check.
+type CheckResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Output string `json:"output,omitempty"`
+}
+
+// Verification records the checks run against the code after the step.
+type Verification struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
 // Step represents an individual step within a session.
 type Step struct {
-	ID        int       `json:"id"`
-	Command   Command   `json:"command"`
-	Timestamp time.Time `json:"timestamp"`
-	FilesDiff FilesDiff `json:"files_diff"`
-	Git       Git       `json:"git"`
+	ID           int           `json:"id"`
+	Type         string        `json:"type,omitempty"`
+	RollbackOf   int           `json:"rollback_of,omitempty"`
+	Command      Command       `json:"command"`
+	Timestamp    time.Time     `json:"timestamp"`
+	FilesDiff    FilesDiff     `json:"files_diff"`
+	Git          Git           `json:"git"`
+	Verification *Verification `json:"verification,omitempty"`
+	SubSteps     []*Step       `json:"sub_steps,omitempty"`
+	Summary      string        `json:"summary,omitempty"` // Analysis of a summary step, which changes no files
+	History      []*Step       `json:"history,omitempty"` // Earlier attempts replaced by replaying the step
+}
+
+// CombinedSnapshots merges the snapshots of the step and its sub-steps into one
+// snapshot per file, from the content before the step to the content after the last sub-step.
+func (s *Step) CombinedSnapshots() []FileSnapshot {
+	combined := []FileSnapshot{}
+	index := map[string]int{}
+
+	steps := append([]*Step{s}, s.SubSteps...)
+	for _, step := range steps {
+		for _, snapshot := range step.FilesDiff.Snapshots {
+			if i, ok := index[snapshot.Path]; ok {
+				combined[i].PostHash = snapshot.PostHash
+				continue
+			}
+
+			index[snapshot.Path] = len(combined)
+			combined = append(combined, snapshot)
+		}
+	}
+
+	return combined
+}
+
+// Isolation describes the git branch, and optionally the separate work tree, a session
+// makes its changes on so they stay off the developer's branch until the session ends.
+type Isolation struct {
+	Branch     string `json:"branch"`             // Branch created for the session
+	BaseBranch string `json:"base_branch"`        // Branch the session started from and is merged back into
+	BaseCommit string `json:"base_commit"`        // Commit the session branch was created at
+	Worktree   string `json:"worktree,omitempty"` // Separate work tree holding the session, if any
+	RepoDir    string `json:"repo_dir,omitempty"` // Work tree of the base branch when the session has its own
 }
 
 // Session represents a user session with an llm.
 type Session struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	CreatedAt   time.Time `json:"created_at"`
-	CompletedAt time.Time `json:"completed_at"`
-	Steps       []*Step   `json:"steps"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt time.Time  `json:"completed_at"`
+	Isolation   *Isolation `json:"isolation,omitempty"`
+	Steps       []*Step    `json:"steps"`
+}
+
+// NextStepID returns the id to use for the next step appended to the session.
+func (s *Session) NextStepID() int {
+	stepID := 1
+	for _, step := range s.Steps {
+		if step.ID >= stepID {
+			stepID = step.ID + 1
+		}
+	}
+
+	return stepID
+}
+
+// FindStep returns the step with the given id.
+func (s *Session) FindStep(id int) (*Step, error) {
+	for _, step := range s.Steps {
+		if step.ID == id {
+			return step, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d", ErrStepNotFound, id)
+}
+
+// RollbackStepFor returns the step that rolled back the given step id, if any.
+func (s *Session) RollbackStepFor(id int) *Step {
+	for _, step := range s.Steps {
+		if step.Type == StepTypeRollback && step.RollbackOf == id {
+			return step
+		}
+	}
+
+	return nil
 }
 
 type StartSessionRequest struct {
-	Name string
-	Dir  string
+	Name      string
+	Dir       string
+	Isolation *Isolation // Branch or work tree already created for the session, if any
 }
 
 // BuildCurrentSessionFilePath is the path to current session file path.
@@ -92,11 +229,40 @@ func BuildCurrentSessionFilePath(path string) string {
 	return filepath.Join(path, sessionFileName)
 }
 
+// IsSessionPath reports whether a slash separated path relative to the session directory
+// is the session file or part of the session history, which holds the blob store.
+func IsSessionPath(relPath string) bool {
+	return relPath == sessionFileName || relPath == sessionHistoryDirName ||
+		strings.HasPrefix(relPath, sessionHistoryDirName+"/")
+}
+
 // BuildSessionHistoryPath is the path to all history sessions.
 func BuildSessionHistoryPath(path string) string {
 	return filepath.Join(path, sessionHistoryDirName)
 }
 
+// FindSessionRoot returns the nearest of dir and its parents that holds a session file, a
+// git repository or a .ca.yaml, so commands run from a subdirectory share the project's
+// session. Without any of them dir itself is the root.
+func FindSessionRoot(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSessionRootResolve, err)
+	}
+
+	for current := absDir; ; current = filepath.Dir(current) {
+		for _, marker := range rootMarkers {
+			if _, err := os.Lstat(filepath.Join(current, marker)); err == nil {
+				return current, nil
+			}
+		}
+
+		if filepath.Dir(current) == current {
+			return absDir, nil
+		}
+	}
+}
+
 // CreateOrCheckSessionDir initializes the session root directory and history dir for storage
 // if they don't exist, returns whether the current session file exists.
 func CreateOrCheckSessionDir(path string) (currentSessionExists bool, err error) {
@@ -147,6 +313,7 @@ func StartSession(startSession *StartSessionRequest) error {
 		ID:        uuid.New().String(),
 		Name:      startSession.Name,
 		CreatedAt: time.Now(),
+		Isolation: startSession.Isolation,
 		Steps:     []*Step{},
 	}
 
@@ -162,6 +329,13 @@ func StartSession(startSession *StartSessionRequest) error {
 
 // EndSession ends the current session and archives it.
 func EndSession(sessionDir string) error {
+	return EndSessionInto(sessionDir, sessionDir)
+}
+
+// EndSessionInto ends the current session of sessionDir and archives it in the history
+// of archiveDir, e.g. when the session's own directory is about to be removed. The blobs
+// of its snapshots are copied along so the archive can still show its diffs.
+func EndSessionInto(sessionDir, archiveDir string) error {
 	// Check if there is an active session
 	sessionFilePath := BuildCurrentSessionFilePath(sessionDir)
 	if _, err := os.Stat(sessionFilePath); os.IsNotExist(err) {
@@ -180,7 +354,17 @@ func EndSession(sessionDir string) error {
 	duration := session.CompletedAt.Sub(session.CreatedAt)
 	fmt.Printf("📅 Session \"%s\" lasted %s\n", session.Name, duration)
 
-	sessionHistoryDirPath := BuildSessionHistoryPath(sessionDir)
+	if archiveDir != sessionDir {
+		if _, err := CreateOrCheckSessionDir(archiveDir); err != nil {
+			return err
+		}
+
+		if err := copySessionBlobs(session, sessionDir, archiveDir); err != nil {
+			return err
+		}
+	}
+
+	sessionHistoryDirPath := BuildSessionHistoryPath(archiveDir)
 	if _, err := os.Stat(sessionHistoryDirPath); os.IsNotExist(err) {
 		return fmt.Errorf("%w: history directory does not exist", ErrSessionMkdir)
 	} else if err != nil {
@@ -213,6 +397,45 @@ func EndSession(sessionDir string) error {
 	return nil
 }
 
+// copySessionBlobs copies the blobs the snapshots of the session refer to from the blob
+// store of one session directory to another.
+func copySessionBlobs(session *Session, fromDir, toDir string) error {
+	from, to := NewBlobStore(fromDir), NewBlobStore(toDir)
+	hashes := map[string]bool{}
+
+	collectBlobHashes(session.Steps, hashes)
+
+	for hash := range hashes {
+		content, err := from.Get(hash)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSessionArchive, err)
+		}
+
+		if _, err := to.Put(content); err != nil {
+			return fmt.Errorf("%w: %v", ErrSessionArchive, err)
+		}
+	}
+
+	return nil
+}
+
+// collectBlobHashes adds the hashes recorded by the steps, their sub-steps and their
+// earlier attempts.
+func collectBlobHashes(steps []*Step, hashes map[string]bool) {
+	for _, step := range steps {
+		for _, snapshot := range step.FilesDiff.Snapshots {
+			for _, hash := range []string{snapshot.PreHash, snapshot.PostHash} {
+				if hash != "" {
+					hashes[hash] = true
+				}
+			}
+		}
+
+		collectBlobHashes(step.SubSteps, hashes)
+		collectBlobHashes(step.History, hashes)
+	}
+}
+
 // LoadCurrentSession will load existing session from the current location.
 func LoadCurrentSession(currentSessionDir string) (*Session, error) {
 	sessionFilePath := BuildCurrentSessionFilePath(currentSessionDir)
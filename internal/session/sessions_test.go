@@ -168,3 +168,54 @@ func TestEndSession_ArchiveFailure(t *testing.T) {
 	_, err = os.Stat(sessionFilePath)
 	assert.NilError(t, err, "Session file should still exist after archive failure.")
 }
+
+// TestStep_CombinedSnapshots verifies sub-steps extend the snapshots of their step.
+func TestStep_CombinedSnapshots(t *testing.T) {
+	step := &session.Step{
+		ID: 1,
+		FilesDiff: session.FilesDiff{Snapshots: []session.FileSnapshot{
+			{Path: "a.go", PreHash: "a0", PostHash: "a1"},
+		}},
+		SubSteps: []*session.Step{
+			{ID: 1, FilesDiff: session.FilesDiff{Snapshots: []session.FileSnapshot{
+				{Path: "a.go", PreHash: "a1", PostHash: "a2"},
+				{Path: "b.go", PostHash: "b1"},
+			}}},
+		},
+	}
+
+	assert.DeepEqual(t, step.CombinedSnapshots(), []session.FileSnapshot{
+		{Path: "a.go", PreHash: "a0", PostHash: "a2"},
+		{Path: "b.go", PostHash: "b1"},
+	})
+}
+
+// TestFindSessionRoot verifies the nearest directory with a session, git repository or
+// .ca.yaml is the root, and that the start directory is used without one.
+func TestFindSessionRoot(t *testing.T) {
+	base := t.TempDir()
+
+	for _, dir := range []string{"plain/sub", "repo/.git", "repo/nested/config/deep", "repo/active/sub"} {
+		assert.NilError(t, os.MkdirAll(filepath.Join(base, filepath.FromSlash(dir)), 0750))
+	}
+
+	assert.NilError(t, os.WriteFile(filepath.Join(base, "repo", "nested", "config", ".ca.yaml"), nil, 0600))
+	assert.NilError(t, os.WriteFile(session.BuildCurrentSessionFilePath(filepath.Join(base, "repo", "active")), nil, 0600))
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"plain/sub", "plain/sub"},
+		{"repo", "repo"},
+		{"repo/nested", "repo"},
+		{"repo/nested/config/deep", "repo/nested/config"},
+		{"repo/active/sub", "repo/active"},
+	}
+
+	for _, tt := range tests {
+		root, err := session.FindSessionRoot(filepath.Join(base, filepath.FromSlash(tt.dir)))
+		assert.NilError(t, err)
+		assert.Equal(t, root, filepath.Join(base, filepath.FromSlash(tt.want)), "root of %s", tt.dir)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+// Package tokens counts the tokens in text so llm calls can be checked against model limits.
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// Encoding used for models tiktoken doesn't know, which is close enough for an estimate.
+const fallbackEncoding = "cl100k_base"
+
+// How long to wait for tiktoken to load its encoding, which is downloaded on first use.
+const loadTimeout = 5 * time.Second
+
+// ErrTokenizerUnavailable is returned along with Estimate when no encoding could be loaded.
+var ErrTokenizerUnavailable = errors.New("tokenizer unavailable, token counts are estimated")
+
+// Context window used for models that aren't listed below.
+const defaultContextWindow = 8192
+
+// Context windows by model name prefix. The longest prefix matching a model wins.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4.1", 1000000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"claude", 200000},
+	{"gemini", 1000000},
+	{"llama3", 8192},
+	{"llama3.1", 128000},
+	{"mistral", 32768},
+	{"deepseek", 64000},
+	{"qwen2.5-coder", 32768},
+}
+
+// Counter returns the number of tokens in text.
+type Counter func(text string) int
+
+// Estimate approximates the number of tokens at about four characters each. It is
+// used when no tokenizer is available.
+func Estimate(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// ForModel returns a tiktoken based counter for the model. On first use tiktoken
+// downloads the encoding over HTTPS and caches it in TIKTOKEN_CACHE_DIR, or the system
+// temp directory when that isn't set. When the encoding can't be loaded before ctx ends
+// or the load timeout passes, e.g. when offline, Estimate is returned with an error
+// saying why. A download already under way can't be interrupted; it finishes in the
+// background and its result is dropped.
+func ForModel(ctx context.Context, model string) (Counter, error) {
+	ctx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+
+	type result struct {
+		encoding *tiktoken.Tiktoken
+		err      error
+	}
+
+	loaded := make(chan result, 1)
+
+	go func() {
+		// Nothing is downloaded once the caller has given up
+		if err := ctx.Err(); err != nil {
+			loaded <- result{err: err}
+			return
+		}
+
+		encoding, err := loadEncoding(model)
+		loaded <- result{encoding: encoding, err: err}
+	}()
+
+	select {
+	case res := <-loaded:
+		if res.err != nil {
+			return Estimate, fmt.Errorf("%w: %v", ErrTokenizerUnavailable, res.err)
+		}
+
+		return func(text string) int {
+			return len(res.encoding.EncodeOrdinary(text))
+		}, nil
+	case <-ctx.Done():
+		return Estimate, fmt.Errorf("%w: %v", ErrTokenizerUnavailable, ctx.Err())
+	}
+}
+
+// loadEncoding loads the encoding of the model, or the fallback encoding for models
+// tiktoken doesn't know.
+func loadEncoding(model string) (*tiktoken.Tiktoken, error) {
+	encoding, err := tiktoken.EncodingForModel(model)
+	if err == nil {
+		return encoding, nil
+	}
+
+	return tiktoken.GetEncoding(fallbackEncoding)
+}
+
+// ContextWindow returns the number of tokens the model accepts for the prompt and
+// response together.
+func ContextWindow(model string) int {
+	best, bestLen := defaultContextWindow, -1
+
+	for _, window := range contextWindows {
+		if strings.HasPrefix(model, window.prefix) && len(window.prefix) > bestLen {
+			best, bestLen = window.tokens, len(window.prefix)
+		}
+	}
+
+	return best
+}
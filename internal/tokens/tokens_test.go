@@ -0,0 +1,51 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package tokens_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/tokens"
+	"gotest.tools/v3/assert"
+)
+
+// TestEstimate verifies the heuristic rounds up to whole tokens.
+func TestEstimate(t *testing.T) {
+	assert.Equal(t, tokens.Estimate(""), 0)
+	assert.Equal(t, tokens.Estimate("abc"), 1)
+	assert.Equal(t, tokens.Estimate("package main"), 3)
+}
+
+// TestContextWindow verifies the most specific model prefix wins.
+func TestContextWindow(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-4o-mini", 128000},
+		{"gpt-4", 8192},
+		{"gpt-4-turbo-preview", 128000},
+		{"claude-3-5-sonnet-latest", 200000},
+		{"llama3", 8192},
+		{"llama3.1:70b", 128000},
+		{"unknown-model", 8192},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tokens.ContextWindow(tt.model), tt.want, tt.model)
+	}
+}
+
+// TestForModel_Cancelled verifies a cancelled load falls back to Estimate and says why.
+func TestForModel_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	count, err := tokens.ForModel(ctx, "gpt-4o")
+	assert.Assert(t, errors.Is(err, tokens.ErrTokenizerUnavailable), "Expected ErrTokenizerUnavailable, got: %v", err)
+	assert.Equal(t, count("package main"), tokens.Estimate("package main"))
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+// Package verify runs checks against code after it has been edited.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Check names.
+const (
+	CheckGoParse = "gofmt"
+	CheckGoBuild = "go build"
+	CheckGoVet   = "go vet"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name   string
+	Passed bool
+	Output string
+}
+
+// Passed reports whether every result passed.
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GoChecks parses the edited Go files and then builds and vets the module in dir.
+// Later checks are skipped once one fails, since their output would only repeat it.
+// Returns no results when none of the files are Go sources.
+func GoChecks(ctx context.Context, dir string, files []string) []Result {
+	goFiles := []string{}
+
+	for _, file := range files {
+		if filepath.Ext(file) == ".go" {
+			goFiles = append(goFiles, file)
+		}
+	}
+
+	if len(goFiles) == 0 {
+		return []Result{}
+	}
+
+	results := []Result{ParseGoFiles(goFiles)}
+	if !results[0].Passed {
+		return results
+	}
+
+	build := RunCommand(ctx, dir, CheckGoBuild, "go", "build", "./...")
+	results = append(results, build)
+
+	if !build.Passed {
+		return results
+	}
+
+	return append(results, RunCommand(ctx, dir, CheckGoVet, "go", "vet", "./..."))
+}
+
+// ParseGoFiles checks that each file is syntactically valid Go, like gofmt -e.
+func ParseGoFiles(files []string) Result {
+	var output strings.Builder
+
+	fset := token.NewFileSet()
+
+	for _, file := range files {
+		if _, err := parser.ParseFile(fset, file, nil, parser.AllErrors); err != nil {
+			fmt.Fprintln(&output, err)
+		}
+	}
+
+	return Result{Name: CheckGoParse, Passed: output.Len() == 0, Output: output.String()}
+}
+
+// RunCommand runs a command in dir and passes when it exits successfully.
+func RunCommand(ctx context.Context, dir, name, command string, args ...string) Result {
+	var output bytes.Buffer
+
+	// nolint:gosec // Why: the commands are chosen by the user or this package
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if output.Len() == 0 {
+			output.WriteString(err.Error())
+		}
+
+		return Result{Name: name, Passed: false, Output: output.String()}
+	}
+
+	return Result{Name: name, Passed: true, Output: output.String()}
+}
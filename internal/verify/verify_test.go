@@ -0,0 +1,48 @@
+// Copyright (c) 2025 - Chris Robinson
+// Licensed under the BSD 3-Clause License.
+// See LICENSE file for details.
+
+package verify_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisrob11/codeassistant/internal/verify"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+// TestGoChecks_StopsOnParseFailure verifies a syntax error is reported without building.
+func TestGoChecks_StopsOnParseFailure(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	assert.NilError(t, os.WriteFile(file, []byte("package main\n\nfunc main() {\n"), 0600))
+
+	results := verify.GoChecks(context.Background(), dir, []string{file})
+	assert.Assert(t, cmp.Len(results, 1))
+	assert.Assert(t, cmp.Equal(results[0].Name, verify.CheckGoParse))
+	assert.Assert(t, !verify.Passed(results))
+	assert.Assert(t, cmp.Contains(results[0].Output, "main.go"))
+}
+
+// TestGoChecks_SkipsNonGoFiles verifies no checks run when no Go files were edited.
+func TestGoChecks_SkipsNonGoFiles(t *testing.T) {
+	results := verify.GoChecks(context.Background(), t.TempDir(), []string{"README.md"})
+	assert.Assert(t, cmp.Len(results, 0))
+	assert.Assert(t, verify.Passed(results))
+}
+
+// TestGoChecks_BuildsModule verifies a valid module passes every check.
+func TestGoChecks_BuildsModule(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0600))
+	assert.NilError(t, os.WriteFile(file, []byte("package main\n\nfunc main() {}\n"), 0600))
+
+	results := verify.GoChecks(context.Background(), dir, []string{file})
+	assert.Assert(t, cmp.Len(results, 3))
+	assert.Assert(t, verify.Passed(results), "%+v", results)
+}